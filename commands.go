@@ -0,0 +1,242 @@
+package elf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/diycoder/elf/config"
+	"github.com/diycoder/elf/plugin"
+	"github.com/diycoder/elf/plugin/version"
+
+	"github.com/urfave/cli/v2"
+)
+
+// commands which can run without initializing the plugins
+var skipInitCommands = map[string]bool{
+	"version": true,
+	"plugins": true,
+}
+
+// exit is replaced in tests
+var exit = os.Exit
+
+// exitAfter runs action and exits, so a built-in command never returns to
+// the caller of Init, which would start the service.
+func exitAfter(action cli.ActionFunc) cli.ActionFunc {
+	return func(ctx *cli.Context) error {
+		if err := action(ctx); err != nil {
+			fmt.Fprintln(ctx.App.ErrWriter, err)
+			exit(1)
+			return err
+		}
+		exit(0)
+		return nil
+	}
+}
+
+func builtinCommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "version",
+			Usage: "Print the build information",
+			Action: exitAfter(func(ctx *cli.Context) error {
+				version.Print(ctx.App.Writer)
+				return nil
+			}),
+		},
+		{
+			Name:  "plugins",
+			Usage: "Inspect the registered plugins",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "list",
+					Usage:  "List the registered plugins",
+					Action: exitAfter(listPlugins),
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Inspect the loaded config",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "dump",
+					Usage: "Dump the loaded config as json",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "name",
+							Usage: "Dump only the named config, e.g. \"default\", \"apollo\", \"nacos\".",
+						},
+					},
+					Action: exitAfter(dumpConfig),
+				},
+			},
+		},
+	}
+}
+
+func listPlugins(ctx *cli.Context) error {
+	w := tabwriter.NewWriter(ctx.App.Writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFLAGS\tCOMMANDS\tHEALTH")
+	for _, p := range plugin.Plugins() {
+		cmds := make([]string, 0, len(p.Commands()))
+		for _, c := range p.Commands() {
+			cmds = append(cmds, c.Name)
+		}
+		_, health := p.(plugin.HealthChecker)
+		fmt.Fprintf(w, "%s\t%d\t%s\t%v\n", p.String(), len(p.Flags()), strings.Join(cmds, ","), health)
+	}
+	return w.Flush()
+}
+
+func dumpConfig(ctx *cli.Context) error {
+	configs := config.Configs()
+	if name := ctx.String("name"); name != "" {
+		c, ok := configs[name]
+		if !ok {
+			return fmt.Errorf("config %s not found", name)
+		}
+		configs = map[string]config.Config{name: c}
+	}
+
+	dump := make(map[string]interface{}, len(configs))
+	for name, c := range configs {
+		dump[name] = maskConfig(c.Map())
+	}
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(ctx.App.Writer, string(b))
+	return err
+}
+
+// configMask replaces the secrets of the dumped config
+const configMask = "******"
+
+var (
+	// configSecretKeys are the keys whose values are masked, compared case
+	// insensitively with path.Match
+	configSecretKeys = []string{
+		"password", "passwd", "*_password",
+		"token", "*_token",
+		"secret", "*_secret",
+		"authorization",
+		"dsn", "*_dsn",
+	}
+	// configSecretRegexp matches the secrets of the config values holding
+	// json, yaml or properties text, e.g. a namespace of apollo
+	configSecretRegexp = regexp.MustCompile(`(?i)(?:password|passwd|secret|token)["']?\s*[:=]\s*["']?([^"'\s,}&]+)`)
+	// credentialRegexp matches the password of a dsn or url, e.g.
+	// user:pass@tcp(host:3306)/db or redis://:pass@host:6379
+	credentialRegexp = regexp.MustCompile(`[^:/@\s"']*:([^@/\s"']+)@`)
+)
+
+// maskConfig returns a copy of v with the values of the secret keys and the
+// secrets embedded in text values masked.
+func maskConfig(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if secretKey(k) {
+				m[k] = configMask
+				continue
+			}
+			m[k] = maskConfig(val)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key := fmt.Sprint(k)
+			if secretKey(key) {
+				m[key] = configMask
+				continue
+			}
+			m[key] = maskConfig(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, val := range v {
+			s[i] = maskConfig(val)
+		}
+		return s
+	case string:
+		return maskSecrets(v)
+	}
+	return v
+}
+
+func secretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range configSecretKeys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecrets masks the first group of the secret regexps in s.
+func maskSecrets(s string) string {
+	for _, re := range []*regexp.Regexp{configSecretRegexp, credentialRegexp} {
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			loc := re.FindStringSubmatchIndex(m)
+			return m[:loc[2]] + configMask + m[loc[3]:]
+		})
+	}
+	return s
+}
+
+// registerCommands appends cmds to the app, a name or alias used twice is an error.
+// skipFailed wraps the actions of the commands of p, so they return an error
+// instead of running when p failed to initialize.
+func skipFailed(p plugin.Plugin, cmds []*cli.Command) []*cli.Command {
+	for _, c := range cmds {
+		if c == nil {
+			continue
+		}
+		c.Subcommands = skipFailed(p, c.Subcommands)
+		if c.Action == nil {
+			continue
+		}
+		action := c.Action
+		c.Action = func(ctx *cli.Context) error {
+			if plugin.StateOf(p) == plugin.StateFailed {
+				return fmt.Errorf("plugin %s failed to initialize", p.String())
+			}
+			return action(ctx)
+		}
+	}
+	return cmds
+}
+
+func registerCommands(app *cli.App, owner string, cmds ...*cli.Command) error {
+	names := map[string]bool{"help": true, "h": true}
+	for _, c := range app.Commands {
+		for _, n := range c.Names() {
+			names[n] = true
+		}
+	}
+
+	for _, c := range cmds {
+		if c == nil {
+			continue
+		}
+		for _, n := range c.Names() {
+			if names[n] {
+				return fmt.Errorf("command %s of %s conflicts with a registered command", n, owner)
+			}
+			names[n] = true
+		}
+		app.Commands = append(app.Commands, c)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package elf
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/diycoder/elf/plugin"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestRegisterCommands(t *testing.T) {
+	app := cli.NewApp()
+	if err := registerCommands(app, "elf", builtinCommands()...); err != nil {
+		t.Fatal(err)
+	}
+	if err := registerCommands(app, "plugin foo", &cli.Command{Name: "foo", Aliases: []string{"f"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registerCommands(app, "plugin bar", &cli.Command{Name: "bar", Aliases: []string{"f"}}); err == nil {
+		t.Fatal("expected alias conflict")
+	}
+	if err := registerCommands(app, "plugin baz", &cli.Command{Name: "version"}); err == nil {
+		t.Fatal("expected builtin conflict")
+	}
+	if err := registerCommands(app, "plugin help", &cli.Command{Name: "help"}); err == nil {
+		t.Fatal("expected help conflict")
+	}
+}
+
+func TestSkipFailed(t *testing.T) {
+	p := plugin.NewPlugin(plugin.WithName("test_skip_failed"))
+	if err := plugin.Register(p); err != nil {
+		t.Fatal(err)
+	}
+	var ran bool
+	cmds := skipFailed(p, []*cli.Command{{Name: "run", Action: func(*cli.Context) error {
+		ran = true
+		return nil
+	}}})
+
+	ctx := cli.NewContext(cli.NewApp(), nil, nil)
+	if err := cmds[0].Action(ctx); err != nil || !ran {
+		t.Fatalf("command did not run: %v", err)
+	}
+	ran = false
+	plugin.SetState(p, plugin.StateFailed)
+	if err := cmds[0].Action(ctx); err == nil || ran {
+		t.Fatal("command of a failed plugin ran")
+	}
+}
+
+func TestMaskConfig(t *testing.T) {
+	dump := map[string]interface{}{
+		"mysql":  map[string]interface{}{"host": "db", "user": "app", "password": "hunter2"},
+		"redis":  map[string]interface{}{"addr": "x:1", "Password": "hunter2", "db": 0},
+		"dsn":    "app:hunter2@tcp(db:3306)/app",
+		"url":    "redis://:hunter2@cache:6379/0",
+		"source": "app:hunter2@tcp(db:3306)/app",
+		"store":  "mysql:\n  auth:\n    password: hunter2\n",
+		"json":   `{"token":"hunter2","name":"auth"}`,
+		"users":  []interface{}{map[string]interface{}{"api_token": "hunter2"}},
+	}
+	b, err := json.Marshal(maskConfig(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "hunter2") {
+		t.Fatalf("secret dumped: %s", b)
+	}
+	for _, want := range []string{`"host":"db"`, `"user":"app"`, `"db":0`, `app:******@tcp(db:3306)`, `\"name\":\"auth\"`} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("%s missing in %s", want, b)
+		}
+	}
+}
+
+func TestExitAfter(t *testing.T) {
+	code := -1
+	defer func() { exit = os.Exit }()
+	exit = func(c int) { code = c }
+
+	app := cli.NewApp()
+	app.ErrWriter = io.Discard
+	ctx := cli.NewContext(app, nil, nil)
+	_ = exitAfter(func(*cli.Context) error { return nil })(ctx)
+	if code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	_ = exitAfter(func(*cli.Context) error { return errors.New("boom") })(ctx)
+	if code != 1 {
+		t.Fatalf("exit code %d", code)
+	}
+}
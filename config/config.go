@@ -3,6 +3,7 @@ package config
 
 import (
 	"context"
+	"sync"
 
 	"github.com/diycoder/elf/config/loader"
 	"github.com/diycoder/elf/config/reader"
@@ -98,11 +99,39 @@ func LoadFile(path string) error {
 var (
 	Loaders  []MakeLoader
 	Watchers map[string]MakeWatcher
+
+	named sync.Map
 )
 
+// Register registers a named config, e.g. the config loaded by a config
+// center plugin, so it can be looked up by diagnostics.
+func Register(name string, c Config) {
+	named.Store(name, c)
+}
+
+// Named returns the config registered with name.
+func Named(name string) (Config, bool) {
+	c, ok := named.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return c.(Config), true
+}
+
+// Configs returns all registered named configs.
+func Configs() map[string]Config {
+	cs := make(map[string]Config)
+	named.Range(func(key, value interface{}) bool {
+		cs[key.(string)] = value.(Config)
+		return true
+	})
+	return cs
+}
+
 func init() {
 	Loaders = make([]MakeLoader, 0)
 	Watchers = make(map[string]MakeWatcher)
+	Register("default", DefaultConfig)
 }
 
 // Watchers a list of watcher
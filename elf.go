@@ -1,8 +1,12 @@
 package elf
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/diycoder/elf/config/cmd"
 	"github.com/diycoder/elf/plugin"
@@ -24,33 +28,111 @@ const (
 	Uninitialized = 0
 )
 
+// Init registers the plugins and runs the cli app, the plugins are
+// initialized once the command line is parsed. A plugin failure is returned
+// as a *plugin.InitError, failures of Optional plugins are only logged.
 func Init(plugins ...plugin.Plugin) error {
+	return initialize(plugins, nil)
+}
+
+// initialize registers the plugins and flags and runs the cli app, flags are
+// the plugin selection flags resolved by Run before the app runs.
+func initialize(plugins []plugin.Plugin, optional map[string]bool, flags ...cli.Flag) error {
 	if !done.CAS(Uninitialized, Initialized) {
 		return ErrorReinitialized
 	}
 
+	policyOf := func(p plugin.Plugin) plugin.Policy {
+		if optional[p.String()] {
+			return plugin.Optional
+		}
+		return plugin.PolicyOf(p)
+	}
+
 	app := cmd.App()
 	oldBefore := app.Before
 	app.Before = func(context *cli.Context) error {
-		for _, p := range plugin.Plugins() {
-			if err := p.Init(context); err != nil {
-				app.CustomAppHelpTemplate = fmt.Sprintf("plugin %s init error: ", p.String())
-				return cli.NewExitError(err, 1)
-			}
+		if skipInitCommands[context.Args().First()] {
+			app.Before = oldBefore
+			return nil
+		}
+
+		if err := initPlugins(context, plugin.Plugins(), policyOf); err != nil {
+			return err
 		}
 		app.Before = oldBefore
 		return nil
 	}
 
+	app.Flags = append(app.Flags, flags...)
+	if err := registerCommands(app, "elf", builtinCommands()...); err != nil {
+		return err
+	}
 	for _, p := range plugins {
 		app.Flags = append(app.Flags, p.Flags()...)
+		if err := registerCommands(app, "plugin "+p.String(), skipFailed(p, p.Commands())...); err != nil {
+			return &plugin.InitError{Plugin: p.String(), Phase: plugin.PhaseRegister, Policy: policyOf(p), Err: err}
+		}
 		if err := plugin.Register(p); err != nil {
-			return err
+			return &plugin.InitError{Plugin: p.String(), Phase: plugin.PhaseRegister, Policy: policyOf(p), Err: err}
 		}
 	}
 	return app.Run(os.Args)
 }
 
+// initPlugins initializes the plugins in order. A failed Optional plugin is
+// marked failed and logged, other failures abort with a *plugin.InitError.
+func initPlugins(ctx *cli.Context, plugins []plugin.Plugin, policyOf func(plugin.Plugin) plugin.Policy) error {
+	start := time.Now()
+	summary := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		begin := time.Now()
+		err := p.Init(ctx)
+		cost := time.Since(begin)
+		if err == nil {
+			plugin.SetState(p, plugin.StateInitialized)
+			summary = append(summary, fmt.Sprintf("%s(%v)", p.String(), cost))
+			continue
+		}
+
+		ierr := &plugin.InitError{
+			Plugin:   p.String(),
+			Phase:    plugin.PhaseInit,
+			Policy:   policyOf(p),
+			Duration: cost,
+			Err:      err,
+		}
+		plugin.SetState(p, plugin.StateFailed)
+		if ierr.Policy != plugin.Optional {
+			log.Errorf("elf init aborted: %v", ierr)
+			return ierr
+		}
+		log.Warnf("elf optional %v", ierr)
+		summary = append(summary, fmt.Sprintf("%s(%v, failed)", p.String(), cost))
+	}
+	log.Infof("elf plugins initialized in %v: %s", time.Since(start), strings.Join(summary, " "))
+	return nil
+}
+
+// Close closes the registered plugins implementing plugin.Closer in the
+// reverse order of registration, so the log plugin is closed last. Plugins
+// which failed to initialize are skipped. It should be called before the
+// process exits.
+func Close(ctx context.Context) error {
+	var errs []error
+	plugins := plugin.Active()
+	for i := len(plugins) - 1; i >= 0; i-- {
+		c, ok := plugins[i].(plugin.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close plugin %s: %w", plugins[i].String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // InitPlugins initialize plugins
 func InitPlugins(plugins ...plugin.Plugin) error {
 	return Init(plugins...)
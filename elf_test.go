@@ -1,12 +1,16 @@
 package elf
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/diycoder/elf/plugin"
 	"github.com/diycoder/elf/plugin/apollo"
 	"github.com/diycoder/elf/plugin/nacos"
 	"github.com/diycoder/elf/plugin/store"
+
+	"github.com/urfave/cli/v2"
 )
 
 func TestPlugin(t *testing.T) {
@@ -59,3 +63,46 @@ func initPlugin() error {
 	}
 	return nil
 }
+
+func TestInitPlugins(t *testing.T) {
+	errInit := errors.New("init failed")
+	failing := func(name string, policy plugin.Policy) plugin.Plugin {
+		return plugin.NewPlugin(plugin.WithName(name), plugin.WithPolicy(policy), plugin.WithInit(func(*cli.Context) error {
+			time.Sleep(time.Millisecond)
+			return errInit
+		}))
+	}
+	ok := plugin.NewPlugin(plugin.WithName("test_ok"))
+	optional := failing("test_optional", plugin.Optional)
+	required := failing("test_required", plugin.Required)
+	for _, p := range []plugin.Plugin{ok, optional, required} {
+		if err := plugin.Register(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := cli.NewContext(cli.NewApp(), nil, nil)
+
+	if err := initPlugins(ctx, []plugin.Plugin{ok, optional}, plugin.PolicyOf); err != nil {
+		t.Fatalf("optional failure aborted the init: %v", err)
+	}
+	if plugin.StateOf(ok) != plugin.StateInitialized || plugin.StateOf(optional) != plugin.StateFailed {
+		t.Fatalf("unexpected states %v %v", plugin.StateOf(ok), plugin.StateOf(optional))
+	}
+	for _, p := range plugin.Active() {
+		if p == optional {
+			t.Fatal("failed optional plugin is active")
+		}
+	}
+
+	err := initPlugins(ctx, []plugin.Plugin{ok, required}, plugin.PolicyOf)
+	var ierr *plugin.InitError
+	if !errors.As(err, &ierr) {
+		t.Fatalf("expected init error, got %v", err)
+	}
+	if ierr.Plugin != "test_required" || ierr.Phase != plugin.PhaseInit || ierr.Policy != plugin.Required {
+		t.Fatalf("unexpected init error %+v", ierr)
+	}
+	if ierr.Duration < time.Millisecond || !errors.Is(err, errInit) {
+		t.Fatalf("unexpected init error %+v", ierr)
+	}
+}
@@ -1,6 +1,8 @@
 package grpool
 
 import (
+	"runtime/debug"
+	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
@@ -45,8 +47,35 @@ func (p *GrPool) Release() error {
 	return nil
 }
 
+// PanicHandler handles the panic of a task, stack is the stack of the
+// panicking goroutine.
+type PanicHandler func(p interface{}, stack []byte)
+
+// taskPanicHandler holds the PanicHandler, it may be replaced while the
+// tasks run
+var taskPanicHandler atomic.Value
+
+func init() {
+	taskPanicHandler.Store(PanicHandler(logPanic))
+}
+
+// SetPanicHandler replaces the handler logging the panics of the tasks with
+// their stack, e.g. to write a crash report. nil restores the default one.
+func SetPanicHandler(h PanicHandler) {
+	if h == nil {
+		h = logPanic
+	}
+	taskPanicHandler.Store(h)
+}
+
+func logPanic(p interface{}, stack []byte) {
+	log.Errorf("Task error: %v\n%s", p, stack)
+}
+
+// panicHandler is called by the pools in the deferred recover, so the stack
+// includes the panicking frames.
 func panicHandler(i interface{}) {
-	log.Errorf("Task error: %v", i)
+	taskPanicHandler.Load().(PanicHandler)(i, debug.Stack())
 }
 
 func NewPool(size int) (*GrPool, error) {
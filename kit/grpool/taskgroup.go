@@ -75,6 +75,7 @@ func runTaskE(ctx context.Context, pool *GrPool, dtype DispatchType, isBreak boo
 		t := func() {
 			defer func() {
 				if e := recover(); e != nil {
+					panicHandler(e)
 					errCh <- fmt.Errorf("got error: %+v", e)
 				}
 			}()
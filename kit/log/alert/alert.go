@@ -0,0 +1,324 @@
+// Package alert sends the log entries at or above a level to notifiers, e.g.
+// a webhook or a DingTalk, Feishu or Slack robot. Identical entries within a
+// window are aggregated into one alert and the notifiers are called in the
+// background through a bounded queue, so a flood of errors neither blocks
+// logging nor the notified services.
+//
+//	a, err := alert.New(alert.WithNotifier(alert.NewDingTalk(url, secret)))
+//	l, err := log.New(log.ZapLogger, log.WithHook(a))
+//	defer a.Close()
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+const (
+	DefaultWindow    = time.Minute
+	DefaultQueueSize = 128
+	DefaultMaxKeys   = 1024
+	DefaultTimeout   = 5 * time.Second
+
+	// maxStack is the bytes of the stack trace in the alert text
+	maxStack = 2048
+)
+
+// Alert is an entry sent to the notifiers. The first entry of a key within
+// the window is sent at once with Count 1, the identical ones following it
+// are sent as one alert with their Count once the window elapsed.
+type Alert struct {
+	nglog.HookEntry
+	Count int
+	// First and Last are the times of the entries counted
+	First time.Time
+	Last  time.Time
+	// Dropped is the number of alerts dropped since the previous one as the
+	// queue was full or too many keys were aggregated
+	Dropped uint64
+}
+
+// Text formats the alert for chat messages.
+func (a *Alert) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(a.Level.String()), a.Message)
+	if a.Count > 1 {
+		fmt.Fprintf(&b, "\nrepeated %d times from %s to %s", a.Count,
+			a.First.Format(nglog.DefaultTimeLayout), a.Last.Format(nglog.DefaultTimeLayout))
+	} else {
+		fmt.Fprintf(&b, "\ntime: %s", a.Time.Format(nglog.DefaultTimeLayout))
+	}
+	if a.Caller != "" {
+		fmt.Fprintf(&b, "\ncaller: %s", a.Caller)
+	}
+
+	keys := make([]string, 0, len(a.Fields))
+	for k := range a.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %v", k, a.Fields[k])
+	}
+
+	if a.Dropped > 0 {
+		fmt.Fprintf(&b, "\n%d alerts dropped before", a.Dropped)
+	}
+	if a.Stack != "" {
+		stack := a.Stack
+		if len(stack) > maxStack {
+			stack = stack[:maxStack] + "..."
+		}
+		fmt.Fprintf(&b, "\n%s", stack)
+	}
+	return b.String()
+}
+
+func (a *Alert) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Level   string                 `json:"level"`
+		Time    time.Time              `json:"time"`
+		Message string                 `json:"message"`
+		Caller  string                 `json:"caller,omitempty"`
+		Stack   string                 `json:"stack,omitempty"`
+		Fields  map[string]interface{} `json:"fields,omitempty"`
+		Count   int                    `json:"count"`
+		First   time.Time              `json:"first"`
+		Last    time.Time              `json:"last"`
+		Dropped uint64                 `json:"dropped,omitempty"`
+	}{
+		Level:   a.Level.String(),
+		Time:    a.Time,
+		Message: a.Message,
+		Caller:  a.Caller,
+		Stack:   a.Stack,
+		Fields:  a.Fields,
+		Count:   a.Count,
+		First:   a.First,
+		Last:    a.Last,
+		Dropped: a.Dropped,
+	})
+}
+
+// Key is the message, the level and the caller of the entry.
+func Key(e *nglog.HookEntry) string {
+	return e.Level.String() + "\x00" + e.Caller + "\x00" + e.Message
+}
+
+type Config struct {
+	Level     nglog.Level
+	Window    time.Duration
+	QueueSize int
+	// MaxKeys caps the keys aggregated within a window, the entries of the
+	// keys beyond it are dropped
+	MaxKeys   int
+	Timeout   time.Duration
+	Key       func(e *nglog.HookEntry) string
+	Notifiers []Notifier
+	OnError   func(err error)
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Level:     nglog.ErrorLevel,
+		Window:    DefaultWindow,
+		QueueSize: DefaultQueueSize,
+		MaxKeys:   DefaultMaxKeys,
+		Timeout:   DefaultTimeout,
+		Key:       Key,
+	}
+}
+
+// Alerter is a log hook sending the entries at or above Level to the
+// notifiers.
+type Alerter struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	groups  map[string]*group
+	dropped uint64
+
+	queue chan *Alert
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// group counts the entries of a key following the first one in the window.
+type group struct {
+	start time.Time
+	entry *nglog.HookEntry
+	count int
+	first time.Time
+	last  time.Time
+}
+
+// New starts sending the alerts in the background.
+func New(opts ...Option) (*Alerter, error) {
+	cfg := NewConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if len(cfg.Notifiers) == 0 {
+		return nil, errors.New("alert needs a notifier")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = DefaultMaxKeys
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Key == nil {
+		cfg.Key = Key
+	}
+
+	a := &Alerter{
+		cfg:    cfg,
+		groups: make(map[string]*group),
+		queue:  make(chan *Alert, cfg.QueueSize),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+func (a *Alerter) Enabled(lvl nglog.Level) bool {
+	return lvl >= a.cfg.Level
+}
+
+// Fire queues the alert of e unless an identical entry was queued within the
+// window, it never blocks.
+func (a *Alerter) Fire(e *nglog.HookEntry) {
+	now := e.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	key := a.cfg.Key(e)
+
+	a.mu.Lock()
+	g, ok := a.groups[key]
+	if ok && now.Sub(g.start) < a.cfg.Window {
+		if g.count == 0 {
+			g.first = now
+		}
+		g.count++
+		g.last = now
+		a.mu.Unlock()
+		return
+	}
+	if ok {
+		a.flushGroup(key, g)
+	} else if len(a.groups) >= a.cfg.MaxKeys {
+		a.dropped++
+		a.mu.Unlock()
+		return
+	}
+	entry := *e
+	a.groups[key] = &group{start: now, entry: &entry}
+	a.mu.Unlock()
+
+	a.send(&Alert{HookEntry: entry, Count: 1, First: now, Last: now})
+}
+
+// flushGroup removes g and queues the alert of the entries it counted, a.mu
+// is held.
+func (a *Alerter) flushGroup(key string, g *group) {
+	delete(a.groups, key)
+	if g.count == 0 {
+		return
+	}
+	alert := &Alert{HookEntry: *g.entry, Count: g.count, First: g.first, Last: g.last}
+	alert.Time = g.last
+	a.sendLocked(alert)
+}
+
+// flush queues the groups whose window elapsed at now, all of them if force.
+func (a *Alerter) flush(now time.Time, force bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, g := range a.groups {
+		if force || now.Sub(g.start) >= a.cfg.Window {
+			a.flushGroup(key, g)
+		}
+	}
+}
+
+func (a *Alerter) send(alert *Alert) {
+	a.mu.Lock()
+	a.sendLocked(alert)
+	a.mu.Unlock()
+}
+
+func (a *Alerter) sendLocked(alert *Alert) {
+	select {
+	case a.queue <- alert:
+	default:
+		a.dropped++
+	}
+}
+
+func (a *Alerter) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.cfg.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case alert := <-a.queue:
+			a.notify(alert)
+		case now := <-ticker.C:
+			a.flush(now, false)
+		case <-a.quit:
+			// the aggregated entries are sent before exiting
+			a.flush(time.Now(), true)
+			for {
+				select {
+				case alert := <-a.queue:
+					a.notify(alert)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *Alerter) notify(alert *Alert) {
+	a.mu.Lock()
+	alert.Dropped, a.dropped = a.dropped, 0
+	a.mu.Unlock()
+
+	for _, n := range a.cfg.Notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+		err := n.Notify(ctx, alert)
+		cancel()
+		if err != nil && a.cfg.OnError != nil {
+			a.cfg.OnError(err)
+		}
+	}
+}
+
+// Close sends the queued and the aggregated alerts and stops, the entries
+// fired after are dropped.
+func (a *Alerter) Close() error {
+	a.once.Do(func() {
+		close(a.quit)
+		<-a.done
+	})
+	return nil
+}
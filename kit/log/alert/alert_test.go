@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+	urls   []string
+}
+
+func (r *recorder) server(t *testing.T, rsp string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.bodies = append(r.bodies, string(b))
+		r.urls = append(r.urls, req.URL.String())
+		r.mu.Unlock()
+		io.WriteString(w, rsp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAlerter(t *testing.T) {
+	rec := &recorder{}
+	srv := rec.server(t, "ok")
+	wh, err := NewWebhook(srv.URL, `{"msg": {{json .Message}}, "count": {{.Count}}, "user": {{json (index .Fields "user")}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(WithNotifier(wh), WithWindow(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := nglog.New(nglog.ZapLogger, nglog.WithWriter(io.Discard), nglog.WithHook(a),
+		nglog.WithEncoderCfg(nglog.NewEncoderConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not alerted")
+	for i := 0; i < 5; i++ {
+		l.WithField("user", "bob").Error("db down")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"msg": "db down", "count": 1, "user": "bob"}`,
+		`{"msg": "db down", "count": 4, "user": "bob"}`,
+	}
+	if strings.Join(rec.bodies, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("bodies %q", rec.bodies)
+	}
+}
+
+func TestAlerterBounded(t *testing.T) {
+	release := make(chan struct{})
+	var (
+		mu     sync.Mutex
+		alerts []*Alert
+	)
+	a, err := New(
+		WithQueueSize(1),
+		WithNotifier(NotifierFunc(func(ctx context.Context, alert *Alert) error {
+			<-release
+			mu.Lock()
+			alerts = append(alerts, alert)
+			mu.Unlock()
+			return nil
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			a.Fire(&nglog.HookEntry{Level: nglog.ErrorLevel, Message: strings.Repeat("x", i)})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fire blocked")
+	}
+	close(release)
+	a.Close()
+
+	var dropped uint64
+	for _, alert := range alerts {
+		dropped += alert.Dropped
+	}
+	if n := uint64(len(alerts)) + dropped; n != 100 {
+		t.Fatalf("%d alerts and %d dropped", len(alerts), dropped)
+	}
+	if len(alerts) > 2 {
+		t.Fatalf("%d alerts queued", len(alerts))
+	}
+}
+
+func TestRobots(t *testing.T) {
+	alert := &Alert{
+		HookEntry: nglog.HookEntry{Level: nglog.ErrorLevel, Message: "db down", Time: time.Now()},
+		Count:     1,
+	}
+
+	rec := &recorder{}
+	srv := rec.server(t, `{"errcode":0,"errmsg":"ok"}`)
+	if err := NewDingTalk(srv.URL+"/robot/send?access_token=t", "s").Notify(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+	if u := rec.urls[0]; !strings.Contains(u, "access_token=t&timestamp=") || !strings.Contains(u, "&sign=") {
+		t.Fatalf("dingtalk url %s", u)
+	}
+	var ding struct {
+		MsgType string `json:"msgtype"`
+		Text    struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &ding); err != nil {
+		t.Fatal(err)
+	}
+	if ding.MsgType != "text" || !strings.HasPrefix(ding.Text.Content, "[ERROR] db down") {
+		t.Fatalf("dingtalk body %s", rec.bodies[0])
+	}
+
+	rec = &recorder{}
+	srv = rec.server(t, `{"code":19021,"msg":"sign match fail"}`)
+	if err := NewFeishu(srv.URL, "s").Notify(context.Background(), alert); err == nil || !strings.Contains(err.Error(), "sign match fail") {
+		t.Fatalf("feishu error %v", err)
+	}
+	var feishu map[string]interface{}
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &feishu); err != nil {
+		t.Fatal(err)
+	}
+	if feishu["msg_type"] != "text" || feishu["timestamp"] == nil || feishu["sign"] == nil {
+		t.Fatalf("feishu body %s", rec.bodies[0])
+	}
+
+	rec = &recorder{}
+	srv = rec.server(t, "ok")
+	if err := NewSlack(srv.URL).Notify(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rec.bodies[0], `{"text":"[ERROR] db down`) {
+		t.Fatalf("slack body %s", rec.bodies[0])
+	}
+}
@@ -0,0 +1,133 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"text/template"
+)
+
+// Notifier sends an alert, it is called by one goroutine at a time with a
+// context carrying the timeout.
+type Notifier interface {
+	Notify(ctx context.Context, a *Alert) error
+}
+
+type NotifierFunc func(ctx context.Context, a *Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, a *Alert) error {
+	return f(ctx, a)
+}
+
+// DefaultWebhookBody posts the alert as JSON.
+const DefaultWebhookBody = `{{json .}}`
+
+// Webhook posts the alert rendered by a text/template, e.g.
+//
+//	{"title": {{json .Message}}, "count": {{.Count}}, "host": {{json (index .Fields "host")}}}
+//
+// The "json" function encodes a value, "text" formats the alert as Text.
+type Webhook struct {
+	URL    string
+	Header http.Header
+	Client *http.Client
+	tmpl   *template.Template
+}
+
+// NewWebhook creates a webhook posting body to url, an empty body posts the
+// alert as JSON.
+func NewWebhook(url, body string) (*Webhook, error) {
+	if body == "" {
+		body = DefaultWebhookBody
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"text": func(a *Alert) string {
+			return a.Text()
+		},
+	}).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %v", err)
+	}
+	return &Webhook{
+		URL:    url,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		tmpl:   tmpl,
+	}, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, a *Alert) error {
+	var body bytes.Buffer
+	if err := w.tmpl.Execute(&body, a); err != nil {
+		return err
+	}
+	_, err := post(ctx, w.Client, w.URL, w.Header, body.Bytes())
+	return err
+}
+
+// post sends body to url and returns the response body, the non 2xx status
+// codes are errors.
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rsp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return b, fmt.Errorf("alert %s: %s %s", url, rsp.Status, bytes.TrimSpace(b))
+	}
+	return b, nil
+}
+
+// Command runs a local command per alert with the alert as JSON on stdin
+// and ALERT_LEVEL, ALERT_MESSAGE and ALERT_COUNT in the environment.
+type Command struct {
+	Name string
+	Args []string
+}
+
+func NewCommand(name string, args ...string) *Command {
+	return &Command{Name: name, Args: args}
+}
+
+func (c *Command) Notify(ctx context.Context, a *Alert) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Env = append(os.Environ(),
+		"ALERT_LEVEL="+a.Level.String(),
+		"ALERT_MESSAGE="+a.Message,
+		"ALERT_COUNT="+strconv.Itoa(a.Count),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("alert command %s: %v %s", c.Name, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
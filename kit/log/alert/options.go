@@ -0,0 +1,75 @@
+package alert
+
+import (
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithLevel sets the lowest level alerted, default error.
+func WithLevel(lvl nglog.Level) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Level = lvl
+	})
+}
+
+// WithWindow sets the window identical entries are aggregated within.
+func WithWindow(d time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Window = d
+	})
+}
+
+// WithQueueSize sets the alerts queued for the notifiers, the alerts beyond
+// it are dropped.
+func WithQueueSize(size int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.QueueSize = size
+	})
+}
+
+// WithMaxKeys sets the keys aggregated within a window.
+func WithMaxKeys(n int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MaxKeys = n
+	})
+}
+
+// WithTimeout sets the timeout of a notifier call.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Timeout = d
+	})
+}
+
+// WithKey sets the key identical entries share, default Key.
+func WithKey(fn func(e *nglog.HookEntry) string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Key = fn
+	})
+}
+
+// WithNotifier adds notifiers, they are called in order.
+func WithNotifier(n ...Notifier) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Notifiers = append(cfg.Notifiers, n...)
+	})
+}
+
+// WithOnError sets the function the notifier errors are reported to, it
+// should not log at the alerted levels.
+func WithOnError(fn func(err error)) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.OnError = fn
+	})
+}
@@ -0,0 +1,136 @@
+package alert
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var jsonHeader = http.Header{"Content-Type": []string{"application/json"}}
+
+// DingTalk sends the alerts as text messages to a DingTalk robot, Secret
+// signs the requests if the robot requires it.
+type DingTalk struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func NewDingTalk(url, secret string) *DingTalk {
+	return &DingTalk{URL: url, Secret: secret}
+}
+
+func (d *DingTalk) Notify(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": a.Text()},
+	})
+	if err != nil {
+		return err
+	}
+
+	u := d.URL
+	if d.Secret != "" {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		sign := hmacBase64([]byte(d.Secret), ts+"\n"+d.Secret)
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + "timestamp=" + ts + "&sign=" + url.QueryEscape(sign)
+	}
+
+	b, err := post(ctx, d.Client, u, jsonHeader, body)
+	if err != nil {
+		return err
+	}
+	var rsp struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(b, &rsp); err != nil {
+		return fmt.Errorf("alert dingtalk: %v", err)
+	}
+	if rsp.ErrCode != 0 {
+		return fmt.Errorf("alert dingtalk: %d %s", rsp.ErrCode, rsp.ErrMsg)
+	}
+	return nil
+}
+
+// Feishu sends the alerts as text messages to a Feishu (Lark) robot, Secret
+// signs the requests if the robot requires it.
+type Feishu struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func NewFeishu(url, secret string) *Feishu {
+	return &Feishu{URL: url, Secret: secret}
+}
+
+func (f *Feishu) Notify(ctx context.Context, a *Alert) error {
+	msg := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": a.Text()},
+	}
+	if f.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		// the string to sign is the key, the message is empty
+		msg["timestamp"] = ts
+		msg["sign"] = hmacBase64([]byte(ts+"\n"+f.Secret), "")
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b, err := post(ctx, f.Client, f.URL, jsonHeader, body)
+	if err != nil {
+		return err
+	}
+	var rsp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(b, &rsp); err != nil {
+		return fmt.Errorf("alert feishu: %v", err)
+	}
+	if rsp.Code != 0 {
+		return fmt.Errorf("alert feishu: %d %s", rsp.Code, rsp.Msg)
+	}
+	return nil
+}
+
+// Slack sends the alerts to a Slack incoming webhook.
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewSlack(url string) *Slack {
+	return &Slack{URL: url}
+}
+
+func (s *Slack) Notify(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(map[string]string{"text": a.Text()})
+	if err != nil {
+		return err
+	}
+	_, err = post(ctx, s.Client, s.URL, jsonHeader, body)
+	return err
+}
+
+func hmacBase64(key []byte, msg string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+var (
+	contextMu     sync.RWMutex
+	contextKeys   = make(map[string]interface{})
+	baggageFields = make(map[string]string)
+)
+
+// RegisterContextKey makes WithContext emit ctx.Value(key) as field name,
+// e.g. a request id or user id stored in the context by a middleware.
+func RegisterContextKey(name string, key interface{}) {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	contextKeys[name] = key
+}
+
+// RegisterBaggageKey makes WithContext emit the OpenTelemetry baggage
+// member member as field name.
+func RegisterBaggageKey(name, member string) {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+	baggageFields[name] = member
+}
+
+// ContextFields extracts the trace id, span id and registered keys from ctx.
+func ContextFields(ctx context.Context) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if ctx == nil {
+		return fields
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields[TraceIDKey] = sc.TraceID().String()
+		fields[SpanIDKey] = sc.SpanID().String()
+	}
+
+	contextMu.RLock()
+	defer contextMu.RUnlock()
+
+	for name, key := range contextKeys {
+		if v := ctx.Value(key); v != nil {
+			fields[name] = v
+		}
+	}
+	if len(baggageFields) > 0 {
+		bag := baggage.FromContext(ctx)
+		for name, member := range baggageFields {
+			if v := bag.Member(member).Value(); v != "" {
+				fields[name] = v
+			}
+		}
+	}
+	return fields
+}
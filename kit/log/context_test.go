@@ -0,0 +1,46 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDKey struct{}
+
+func TestLoggerWithContext(t *testing.T) {
+	RegisterContextKey("request_id", requestIDKey{})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = context.WithValue(ctx, requestIDKey{}, "req-1")
+
+	buf := new(bytes.Buffer)
+	logger, err := New(ZapLogger, WithWriter(buf), WithEncoder(JSONEncoder), WithEncoderCfg(NewEncoderConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fn := range []func(){
+		func() { logger.InfofCtx(ctx, "hello %s", "ctx") },
+		func() { logger.WithContext(ctx).Info("hello") },
+	} {
+		buf.Reset()
+		fn()
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry[TraceIDKey] != traceID.String() || entry[SpanIDKey] != spanID.String() || entry["request_id"] != "req-1" {
+			t.Fatalf("unexpected entry %v", entry)
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package log
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // LowercaseLevelEncoder serializes a Level to a lowercase string. For example,
 // InfoLevel is serialized to "info".
 // CapitalLevelEncoder serializes a Level to an all-caps string. For example,
@@ -24,6 +30,8 @@ const (
 )
 
 // A TimeEncoder serializes a time.Time to a primitive type.
+// ChinaMilliTimeEncoder serializes in "2006-01-02 15:04:05.000" format,
+// LayoutTimeEncoder in the TimeLayout of the EncoderConfig.
 type TimeEncoder int
 
 const (
@@ -31,8 +39,31 @@ const (
 	RFC3339MilliTimeEncoder
 	RFC3339NanoTimeEncoder
 	ChinaMilliTimeEncoder
+	LayoutTimeEncoder
 )
 
+// DefaultTimeLayout is the layout of ChinaMilliTimeEncoder, and of
+// LayoutTimeEncoder without TimeLayout.
+const DefaultTimeLayout = "2006-01-02 15:04:05.000"
+
+// ParseTimeEncoder parses a time encoder name, e.g. "rfc3339milli" or "layout".
+func ParseTimeEncoder(text string) (TimeEncoder, error) {
+	switch strings.ToLower(text) {
+	case "rfc3339":
+		return RFC3339TimeEncoder, nil
+	case "rfc3339milli":
+		return RFC3339MilliTimeEncoder, nil
+	case "rfc3339nano":
+		return RFC3339NanoTimeEncoder, nil
+	case "china_milli", "chinamilli":
+		return ChinaMilliTimeEncoder, nil
+	case "layout":
+		return LayoutTimeEncoder, nil
+	default:
+		return RFC3339MilliTimeEncoder, fmt.Errorf("Invalid time encoder: %s ", text)
+	}
+}
+
 type Encoder int
 
 const (
@@ -41,11 +72,16 @@ const (
 )
 
 type EncoderConfig struct {
-	MessageKey    string
-	LevelKey      string
-	EncodeLevel   LevelEncoder
-	TimeKey       string
-	EncodeTime    TimeEncoder
+	MessageKey  string
+	LevelKey    string
+	EncodeLevel LevelEncoder
+	TimeKey     string
+	EncodeTime  TimeEncoder
+	// TimeLayout is the layout of LayoutTimeEncoder
+	TimeLayout string
+	// TimeLocation converts the times before encoding, nil keeps the
+	// location of the entries, which is time.Local
+	TimeLocation  *time.Location
 	CallerKey     string
 	EncodeCaller  CallerEncoder
 	StacktraceKey string
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field is a typed key-value pair. It is the zap field itself, so fields are
+// handed to the zap core without conversion.
+type Field = zapcore.Field
+
+// String constructs a field with the given key and value.
+func String(key string, val string) Field {
+	return zap.String(key, val)
+}
+
+// Int constructs a field with the given key and value.
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a field with the given key and value.
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Float64 constructs a field with the given key and value.
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Bool constructs a field with the given key and value.
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Duration constructs a field with the given key and value.
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Time constructs a field with the given key and value.
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// Err constructs a field that carries an error under the key "error".
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// Any takes a key and an arbitrary value and chooses the best way to
+// represent them as a field.
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
+
+const badKey = "!BADKEY"
+
+// KeysAndValues converts loosely-typed key-value pairs to fields, Field
+// values in the list are kept as is.
+func KeysAndValues(kv ...interface{}) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(kv)/2+1)
+	for i := 0; i < len(kv); {
+		if f, ok := kv[i].(Field); ok {
+			fields = append(fields, f)
+			i++
+			continue
+		}
+
+		// a dangling key is logged as the value of a bad key
+		if i == len(kv)-1 {
+			fields = append(fields, zap.Any(badKey, kv[i]))
+			break
+		}
+
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields = append(fields, zap.Any(key, kv[i+1]))
+		i += 2
+	}
+	return fields
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoggerInfow(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger, err := New(ZapLogger, WithWriter(buf), WithEncoder(JSONEncoder), WithEncoderCfg(NewEncoderConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.With(String("svc", "elf")).Infow("request done",
+		"status", 200,
+		Duration("latency", 1500*time.Millisecond),
+		Err(errors.New("boom")),
+		"dangling",
+	)
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"msg":     "request done",
+		"svc":     "elf",
+		"status":  float64(200),
+		"latency": float64(1500 * time.Millisecond),
+		"error":   "boom",
+		badKey:    "dangling",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Fatalf("field %s: want %v, got %v", k, v, entry[k])
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// HookEntry is an entry handed to the hooks, Fields hold the fields of the
+// entry and of the logger it was written by.
+type HookEntry struct {
+	Level   Level
+	Time    time.Time
+	Message string
+	Caller  string
+	Stack   string
+	Fields  map[string]interface{}
+}
+
+// Hook is called with the entries of the levels it enables, Fire is called
+// on the logging goroutine and must not block.
+type Hook interface {
+	Enabled(lvl Level) bool
+	Fire(e *HookEntry)
+}
+
+// hookCore is teed next to the core of a logger, it hands the entries to the
+// hooks instead of writing them.
+type hookCore struct {
+	hooks    []Hook
+	fields   []zapcore.Field
+	redactor *Redactor
+}
+
+// NewHookCore creates a core handing the entries to hooks, the message and
+// the fields are masked by r if not nil. Tee it next to the cores the entries
+// are written to, see WithZapCore.
+func NewHookCore(r *Redactor, hooks ...Hook) zapcore.Core {
+	return &hookCore{hooks: hooks, redactor: r}
+}
+
+func (c *hookCore) Enabled(lvl zapcore.Level) bool {
+	for _, h := range c.hooks {
+		if h.Enabled(Level(lvl)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fs := range [][]zapcore.Field{c.fields, fields} {
+		if c.redactor != nil {
+			fs = c.redactor.fields(fs)
+		}
+		for _, f := range fs {
+			f.AddTo(enc)
+		}
+	}
+
+	e := &HookEntry{
+		Level:   Level(ent.Level),
+		Time:    ent.Time,
+		Message: ent.Message,
+		Stack:   ent.Stack,
+		Fields:  enc.Fields,
+	}
+	if c.redactor != nil {
+		e.Message = c.redactor.Value(e.Message)
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	for _, h := range c.hooks {
+		if h.Enabled(e.Level) {
+			h.Fire(e)
+		}
+	}
+	return nil
+}
+
+func (c *hookCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"io"
+	"testing"
+)
+
+type testHook struct {
+	level   Level
+	entries []*HookEntry
+}
+
+func (h *testHook) Enabled(lvl Level) bool {
+	return lvl >= h.level
+}
+
+func (h *testHook) Fire(e *HookEntry) {
+	h.entries = append(h.entries, e)
+}
+
+func TestHook(t *testing.T) {
+	h := &testHook{level: WarnLevel}
+	l, err := New(ZapLogger,
+		WithWriter(io.Discard),
+		WithEncoderCfg(NewEncoderConfig()),
+		WithRedactor(newTestRedactor(t)),
+		WithHook(h),
+		AddCaller(),
+		Fields(map[string]interface{}{"project": "elf"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("skipped")
+	l.WithField("password", "secret").Warnw("login failed for 13812345678", "user", "bob")
+	l.Errorf("timeout after %ds", 3)
+
+	if len(h.entries) != 2 {
+		t.Fatalf("%d entries", len(h.entries))
+	}
+	e := h.entries[0]
+	if e.Level != WarnLevel || e.Message != "login failed for 138****5678" || e.Caller == "" {
+		t.Fatalf("entry %+v", e)
+	}
+	if e.Fields["password"] != "******" || e.Fields["user"] != "bob" || e.Fields["project"] != "elf" {
+		t.Fatalf("fields %v", e.Fields)
+	}
+	if e := h.entries[1]; e.Level != ErrorLevel || e.Message != "timeout after 3s" || e.Stack == "" {
+		t.Fatalf("entry %+v", e)
+	}
+}
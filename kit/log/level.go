@@ -1,6 +1,9 @@
 package log
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Level int
 
@@ -44,6 +47,27 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel parses a level from its lower-case ASCII representation,
+// "trace" is accepted as DebugLevel.
+func ParseLevel(text string) (Level, error) {
+	switch strings.ToLower(text) {
+	case "debug", "trace":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "panic":
+		return PanicLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("Invalid level: %s ", text)
+	}
+}
+
 // Enabled returns true if the given level is at or above this level.
 func (l Level) Enabled(lvl Level) bool {
 	return lvl >= l
@@ -1,6 +1,9 @@
 package log
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Logger interface {
 	Debug(args ...interface{})
@@ -18,10 +21,34 @@ type Logger interface {
 	Fatal(args ...interface{})
 	Fatalf(format string, args ...interface{})
 
+	// w variants log a message with loosely-typed key-value pairs, Field
+	// values (String, Int, Err...) may be mixed in.
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+
+	// Ctx variants emit the trace_id, span_id and registered context keys
+	// of ctx as fields, see ContextFields.
+	DebugCtx(ctx context.Context, args ...interface{})
+	DebugfCtx(ctx context.Context, format string, args ...interface{})
+	InfoCtx(ctx context.Context, args ...interface{})
+	InfofCtx(ctx context.Context, format string, args ...interface{})
+	WarnCtx(ctx context.Context, args ...interface{})
+	WarnfCtx(ctx context.Context, format string, args ...interface{})
+	ErrorCtx(ctx context.Context, args ...interface{})
+	ErrorfCtx(ctx context.Context, format string, args ...interface{})
+
 	WithField(key string, value interface{}) Logger
 	WithFields(fields map[string]interface{}) Logger
+	WithContext(ctx context.Context) Logger
+	// With adds typed fields, it does not convert them like WithFields.
+	With(fields ...Field) Logger
 
 	SetLogLevel(level Level) error
+	// Sync flushes the buffered entries, e.g. of an async writer, it should
+	// be called before the process exits.
+	Sync() error
 }
 
 func New(Type LoggerType, opts ...Option) (Logger, error) {
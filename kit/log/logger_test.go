@@ -1,8 +1,11 @@
 package log
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogger(t *testing.T) {
@@ -32,3 +35,58 @@ func defaultZapEncoderCfg() EncoderConfig {
 		StacktraceKey: "detail",
 	}
 }
+
+func TestLoggerTimeEncoder(t *testing.T) {
+	if _, err := ParseTimeEncoder("unknown"); err == nil {
+		t.Fatal("unknown time encoder is parsed")
+	}
+	enc, err := ParseTimeEncoder("layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	cfg := defaultZapEncoderCfg()
+	cfg.EncodeTime = enc
+	cfg.TimeLayout = "MST -0700"
+	cfg.TimeLocation = time.FixedZone("TST", 3600)
+	logger, err := New(0,
+		WithWriter(&buf),
+		WithEncoder(JSONEncoder),
+		WithEncoderCfg(cfg),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("haha")
+	if got := buf.String(); !strings.Contains(got, `"@timestamp":"TST +0100"`) {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestLoggerFatal(t *testing.T) {
+	code := -1
+	defer func() { exit = os.Exit }()
+	exit = func(c int) { code = c }
+
+	buf := new(bytes.Buffer)
+	logger, err := New(ZapLogger, WithWriter(buf), WithEncoderCfg(NewEncoderConfig()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.WithField("port", 8080).Fatalf("cannot listen on %d", 8080)
+	if code != 1 || !strings.Contains(buf.String(), "cannot listen on 8080") {
+		t.Fatalf("exit code %d, log %q", code, buf.String())
+	}
+
+	// the hook replaces the exit, also for the derived loggers
+	code, hooked := -1, 0
+	logger, err = New(ZapLogger, WithWriter(buf), WithFatalHook(func() { hooked++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.With(String("svc", "user")).Fatal("stop")
+	if code != -1 || hooked != 1 {
+		t.Fatalf("exit code %d, hooked %d", code, hooked)
+	}
+}
@@ -0,0 +1,126 @@
+// Package logtest records the log entries in memory so tests can assert on
+// them instead of parsing the output:
+//
+//	lt := logtest.New()
+//	old := log.GetLogger()
+//	log.SetLogger(lt)
+//	defer log.SetLogger(old)
+//
+//	...
+//	lt.AssertLogged(t, nglog.ErrorLevel, "query failed", map[string]interface{}{"table": "users"})
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry is a recorded entry, Fields hold the fields of the entry and of the
+// logger it was written by.
+type Entry struct {
+	Level   nglog.Level
+	Message string
+	Fields  map[string]interface{}
+	Time    time.Time
+	Caller  string
+}
+
+// Logger is a nglog.Logger recording every entry, panic and fatal entries
+// are recorded at error level as the zap logger writes them, fatal does not
+// exit the test.
+type Logger struct {
+	nglog.Logger
+	logs *observer.ObservedLogs
+}
+
+// New creates a logger recording the entries of all levels.
+func New() *Logger {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l, err := nglog.NewZapLogger(
+		nglog.WithZapCore(core),
+		nglog.WithZapOptions([]zap.Option{zap.AddCaller(), zap.AddCallerSkip(1)}),
+		nglog.WithZapFatalHook(func() {}),
+	)
+	if err != nil {
+		// the options are fixed
+		panic(err)
+	}
+	return &Logger{Logger: l, logs: logs}
+}
+
+// Entries returns the recorded entries in order.
+func (l *Logger) Entries() []Entry {
+	logged := l.logs.All()
+	entries := make([]Entry, 0, len(logged))
+	for _, e := range logged {
+		entry := Entry{
+			Level:   nglog.Level(e.Level),
+			Message: e.Message,
+			Fields:  e.ContextMap(),
+			Time:    e.Time,
+		}
+		if e.Caller.Defined {
+			entry.Caller = e.Caller.TrimmedPath()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Filter returns the entries of level containing msgSubstring and fields,
+// the field values are compared by their fmt.Sprint form.
+func (l *Logger) Filter(level nglog.Level, msgSubstring string, fields map[string]interface{}) []Entry {
+	var matched []Entry
+	for _, e := range l.Entries() {
+		if e.Level == level && strings.Contains(e.Message, msgSubstring) && e.has(fields) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Reset drops the recorded entries, e.g. between sub tests.
+func (l *Logger) Reset() {
+	l.logs.TakeAll()
+}
+
+// AssertLogged fails t unless an entry matches, see Filter.
+func (l *Logger) AssertLogged(t testing.TB, level nglog.Level, msgSubstring string, fields map[string]interface{}) {
+	t.Helper()
+	if len(l.Filter(level, msgSubstring, fields)) == 0 {
+		t.Errorf("no %s entry with %q and fields %v is logged, got:\n%s", level, msgSubstring, fields, l.dump())
+	}
+}
+
+// AssertNotLogged fails t if an entry matches, see Filter.
+func (l *Logger) AssertNotLogged(t testing.TB, level nglog.Level, msgSubstring string, fields map[string]interface{}) {
+	t.Helper()
+	if matched := l.Filter(level, msgSubstring, fields); len(matched) > 0 {
+		t.Errorf("%d %s entries with %q and fields %v are logged", len(matched), level, msgSubstring, fields)
+	}
+}
+
+func (e Entry) has(fields map[string]interface{}) bool {
+	for k, want := range fields {
+		got, ok := e.Fields[k]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Logger) dump() string {
+	var b strings.Builder
+	for _, e := range l.Entries() {
+		fmt.Fprintf(&b, "\t%s %s %v\n", e.Level, e.Message, e.Fields)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package logtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+func TestLogger(t *testing.T) {
+	lt := New()
+	var l nglog.Logger = lt
+
+	l.Infow("user login", "user_id", 7, "ok", true)
+	l.WithField("table", "users").Errorf("query failed: %v", errors.New("timeout"))
+	l.Debug("verbose")
+
+	lt.AssertLogged(t, nglog.InfoLevel, "login", map[string]interface{}{"user_id": 7, "ok": true})
+	lt.AssertLogged(t, nglog.ErrorLevel, "query failed: timeout", map[string]interface{}{"table": "users"})
+	lt.AssertLogged(t, nglog.DebugLevel, "verbose", nil)
+	lt.AssertNotLogged(t, nglog.WarnLevel, "", nil)
+	lt.AssertNotLogged(t, nglog.InfoLevel, "login", map[string]interface{}{"user_id": 8})
+
+	entries := lt.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("%d entries", len(entries))
+	}
+	if !strings.HasPrefix(entries[0].Caller, "logtest/logtest_test.go:") {
+		t.Errorf("caller %s", entries[0].Caller)
+	}
+
+	lt.Reset()
+	if n := len(lt.Entries()); n != 0 {
+		t.Fatalf("%d entries after reset", n)
+	}
+
+	ft := &fakeT{TB: t}
+	lt.AssertLogged(ft, nglog.InfoLevel, "login", nil)
+	if !ft.failed {
+		t.Fatal("assert after reset should fail")
+	}
+}
+
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
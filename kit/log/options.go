@@ -15,11 +15,16 @@ type Options struct {
 	ErrWriter    io.Writer
 	Fields       map[string]interface{}
 	LevelEnabler Level
+	MaxLevel     Level
 	AddStack     Level
 	AddCaller    bool
 	CallerSkip   int
 	EncoderCfg   EncoderConfig
 	Encoder      Encoder
+	Sampling     *SamplingConfig
+	Redactor     *Redactor
+	Hooks        []Hook
+	FatalHook    func()
 }
 
 type Option interface {
@@ -40,6 +45,44 @@ func WithLevelEnabler(lvl Level) Option {
 	})
 }
 
+// WithMaxLevel sets the highest level written, together with WithLevelEnabler
+// it restricts a logger to a level range, e.g. debug to info.
+func WithMaxLevel(lvl Level) Option {
+	return optionFunc(func(opts *Options) {
+		opts.MaxLevel = lvl
+	})
+}
+
+// WithSampling caps the entries written per message and level, see
+// SamplingConfig.
+func WithSampling(cfg SamplingConfig) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Sampling = &cfg
+	})
+}
+
+// WithRedactor masks sensitive data in the message and the fields of the
+// entries, see Redactor.
+func WithRedactor(r *Redactor) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Redactor = r
+	})
+}
+
+// WithHook hands the entries of the levels h enables to h, see Hook.
+func WithHook(h Hook) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Hooks = append(opts.Hooks, h)
+	})
+}
+
+// WithFatalHook runs fn instead of exiting after a fatal entry is written.
+func WithFatalHook(fn func()) Option {
+	return optionFunc(func(opts *Options) {
+		opts.FatalHook = fn
+	})
+}
+
 func WithWriter(writer io.Writer) Option {
 	return optionFunc(func(opts *Options) {
 		opts.Writer = writer
@@ -0,0 +1,463 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// MaskStyle is how a sensitive value is masked.
+type MaskStyle int
+
+const (
+	// MaskAll replaces the value with a fixed mask hiding its length
+	MaskAll MaskStyle = iota
+	// MaskPartial keeps the Head and Tail characters, e.g. 138****5678
+	MaskPartial
+	// MaskHash replaces the value with a short sha256, so equal values can
+	// still be matched
+	MaskHash
+)
+
+const maskAll = "******"
+
+func (s MaskStyle) String() string {
+	switch s {
+	case MaskAll:
+		return "all"
+	case MaskPartial:
+		return "partial"
+	case MaskHash:
+		return "hash"
+	}
+	return fmt.Sprintf("MaskStyle(%d)", int(s))
+}
+
+// ParseMaskStyle parses "all", "partial" or "hash".
+func ParseMaskStyle(text string) (MaskStyle, error) {
+	switch strings.ToLower(text) {
+	case "", "all":
+		return MaskAll, nil
+	case "partial":
+		return MaskPartial, nil
+	case "hash":
+		return MaskHash, nil
+	}
+	return MaskAll, fmt.Errorf("Invalid mask style: %v ", text)
+}
+
+// Mask masks a value in a style.
+type Mask struct {
+	Style MaskStyle
+	Head  int
+	Tail  int
+}
+
+// Apply returns the masked s.
+func (m Mask) Apply(s string) string {
+	switch m.Style {
+	case MaskPartial:
+		r := []rune(s)
+		if m.Head < 0 || m.Tail < 0 || len(r) <= m.Head+m.Tail {
+			return maskAll
+		}
+		return string(r[:m.Head]) + strings.Repeat("*", len(r)-m.Head-m.Tail) + string(r[len(r)-m.Tail:])
+	case MaskHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return maskAll
+}
+
+// KeyRule masks the values of the fields whose key matches Pattern, a case
+// insensitive glob such as "password" or "*_secret". Values which are not
+// strings are always masked with MaskAll.
+type KeyRule struct {
+	Pattern string
+	Mask    Mask
+}
+
+// ValueRule masks the parts of string values matching Regexp. When Regexp
+// has a group only the first group is masked, e.g. the name of an email.
+// Valid, if set, rejects false positives such as numbers failing a checksum.
+type ValueRule struct {
+	Name   string
+	Regexp *regexp.Regexp
+	Mask   Mask
+	Valid  func(string) bool
+}
+
+// DefaultRedactKeys are the keys masked by DefaultKeyRules.
+var DefaultRedactKeys = []string{
+	"password", "passwd", "*_password",
+	"token", "*_token",
+	"secret", "*_secret",
+	"authorization",
+}
+
+// DefaultKeyRules masks the values of DefaultRedactKeys.
+func DefaultKeyRules() []KeyRule {
+	rules := make([]KeyRule, len(DefaultRedactKeys))
+	for i, key := range DefaultRedactKeys {
+		rules[i] = KeyRule{Pattern: key}
+	}
+	return rules
+}
+
+var (
+	emailRegexp    = regexp.MustCompile(`\b([A-Za-z0-9._%+-]+)@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+	idCardRegexp   = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	bankCardRegexp = regexp.MustCompile(`\b\d{16,19}\b`)
+	mobileRegexp   = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+)
+
+// ValueRuleNames are the names of the builtin value rules, in the order
+// they should be applied.
+var ValueRuleNames = []string{"email", "id_card", "bank_card", "mobile"}
+
+// NewValueRule returns a builtin value rule: "email", "id_card" (Chinese
+// resident ID), "bank_card" (16-19 digits passing the Luhn check) or
+// "mobile" (Chinese mobile number).
+func NewValueRule(name string) (ValueRule, error) {
+	switch name {
+	case "email":
+		return ValueRule{Name: name, Regexp: emailRegexp, Mask: Mask{Style: MaskPartial, Head: 1}}, nil
+	case "id_card":
+		return ValueRule{Name: name, Regexp: idCardRegexp, Mask: Mask{Style: MaskPartial, Head: 3, Tail: 4}, Valid: validIDCard}, nil
+	case "bank_card":
+		return ValueRule{Name: name, Regexp: bankCardRegexp, Mask: Mask{Style: MaskPartial, Head: 6, Tail: 4}, Valid: validLuhn}, nil
+	case "mobile":
+		return ValueRule{Name: name, Regexp: mobileRegexp, Mask: Mask{Style: MaskPartial, Head: 3, Tail: 4}}, nil
+	}
+	return ValueRule{}, fmt.Errorf("Invalid value rule: %v ", name)
+}
+
+// validIDCard checks the GB 11643 check digit.
+func validIDCard(s string) bool {
+	weights := [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+	return "10X98765432"[sum%11] == s[17] || (s[17] == 'x' && sum%11 == 2)
+}
+
+func validLuhn(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Redactor masks sensitive data by the key of a field and by its value.
+type Redactor struct {
+	keys   []KeyRule
+	values []ValueRule
+}
+
+// NewRedactor creates a redactor, the value rules are applied in order.
+func NewRedactor(keys []KeyRule, values []ValueRule) (*Redactor, error) {
+	r := &Redactor{values: values}
+	for _, rule := range keys {
+		rule.Pattern = strings.ToLower(rule.Pattern)
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid key rule: %v ", rule.Pattern)
+		}
+		r.keys = append(r.keys, rule)
+	}
+	for _, rule := range values {
+		if rule.Regexp == nil {
+			return nil, fmt.Errorf("Invalid value rule: %v ", rule.Name)
+		}
+	}
+	return r, nil
+}
+
+// keyMask returns the mask of the first key rule matching key.
+func (r *Redactor) keyMask(key string) (Mask, bool) {
+	if len(r.keys) == 0 {
+		return Mask{}, false
+	}
+	key = strings.ToLower(key)
+	for _, rule := range r.keys {
+		if ok, _ := path.Match(rule.Pattern, key); ok {
+			return rule.Mask, true
+		}
+	}
+	return Mask{}, false
+}
+
+// Value masks the parts of s matched by the value rules.
+func (r *Redactor) Value(s string) string {
+	for _, rule := range r.values {
+		s = rule.apply(s)
+	}
+	return s
+}
+
+func (rule ValueRule) apply(s string) string {
+	matches := rule.Regexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if rule.Valid != nil && !rule.Valid(s[m[0]:m[1]]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(rule.Mask.Apply(s[start:end]))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// String masks the value of a string field.
+func (r *Redactor) String(key, value string) string {
+	if m, ok := r.keyMask(key); ok {
+		return m.Apply(value)
+	}
+	return r.Value(value)
+}
+
+// Field masks a field. Reflected values are masked through their JSON form,
+// the key rules apply to the keys of nested objects.
+func (r *Redactor) Field(f Field) Field {
+	f, _ = r.field(f)
+	return f
+}
+
+func (r *Redactor) field(f Field) (Field, bool) {
+	if m, ok := r.keyMask(f.Key); ok && f.Type != zapcore.SkipType {
+		switch f.Type {
+		case zapcore.StringType:
+			return zap.String(f.Key, m.Apply(f.String)), true
+		case zapcore.ByteStringType:
+			return zap.String(f.Key, m.Apply(string(f.Interface.([]byte)))), true
+		}
+		return zap.String(f.Key, maskAll), true
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		if v := r.Value(f.String); v != f.String {
+			return zap.String(f.Key, v), true
+		}
+	case zapcore.ByteStringType:
+		b := string(f.Interface.([]byte))
+		if v := r.Value(b); v != b {
+			return zap.String(f.Key, v), true
+		}
+	case zapcore.ReflectType:
+		if v, ok := r.reflected(f.Interface); ok {
+			return zap.Reflect(f.Key, v), true
+		}
+	case zapcore.ObjectMarshalerType:
+		return zap.Object(f.Key, redactObject{f.Interface.(zapcore.ObjectMarshaler), r}), true
+	}
+	return f, false
+}
+
+// fields masks fields, the slice is copied only when a field changes.
+func (r *Redactor) fields(fields []Field) []Field {
+	var out []Field
+	for i, f := range fields {
+		rf, changed := r.field(f)
+		if out == nil && changed {
+			out = make([]Field, len(fields))
+			copy(out, fields[:i])
+		}
+		if out != nil {
+			out[i] = rf
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// reflected masks v through its JSON form, it reports false when nothing is
+// masked.
+func (r *Redactor) reflected(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var x interface{}
+	if err := dec.Decode(&x); err != nil {
+		return v, false
+	}
+	return r.walk(x)
+}
+
+func (r *Redactor) walk(v interface{}) (interface{}, bool) {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if m, ok := r.keyMask(k); ok {
+				if s, ok := e.(string); ok {
+					v[k] = m.Apply(s)
+				} else {
+					v[k] = maskAll
+				}
+				changed = true
+				continue
+			}
+			if e, ok := r.walk(e); ok {
+				v[k] = e
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if e, ok := r.walk(e); ok {
+				v[i] = e
+				changed = true
+			}
+		}
+	case string:
+		if s := r.Value(v); s != v {
+			return s, true
+		}
+	}
+	return v, changed
+}
+
+type redactObject struct {
+	m zapcore.ObjectMarshaler
+	r *Redactor
+}
+
+func (o redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.m.MarshalLogObject(&redactObjectEncoder{enc, o.r})
+}
+
+// redactObjectEncoder masks the values added to an object encoder.
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *Redactor
+}
+
+func (e *redactObjectEncoder) masked(key string) bool {
+	if _, ok := e.r.keyMask(key); ok {
+		e.ObjectEncoder.AddString(key, maskAll)
+		return true
+	}
+	return false
+}
+
+func (e *redactObjectEncoder) AddString(key, value string) {
+	e.ObjectEncoder.AddString(key, e.r.String(key, value))
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, value []byte) {
+	e.ObjectEncoder.AddString(key, e.r.String(key, string(value)))
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, value []byte) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddBinary(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, value int64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt32(key string, value int32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, value uint64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint32(key string, value uint32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, value float64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddFloat64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, value interface{}) error {
+	if e.masked(key) {
+		return nil
+	}
+	value, _ = e.r.reflected(value)
+	return e.ObjectEncoder.AddReflected(key, value)
+}
+
+func (e *redactObjectEncoder) AddObject(key string, value zapcore.ObjectMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactObject{value, e.r})
+}
+
+func (e *redactObjectEncoder) AddArray(key string, value zapcore.ArrayMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, value)
+}
+
+// redactEncoder masks the message and the fields of the entries, the fields
+// added by With are masked as they are added.
+type redactEncoder struct {
+	redactObjectEncoder
+	enc zapcore.Encoder
+}
+
+func newRedactEncoder(enc zapcore.Encoder, r *Redactor) zapcore.Encoder {
+	return &redactEncoder{redactObjectEncoder{enc, r}, enc}
+}
+
+func (e *redactEncoder) Clone() zapcore.Encoder {
+	return newRedactEncoder(e.enc.Clone(), e.r)
+}
+
+func (e *redactEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ent.Message = e.r.Value(ent.Message)
+	return e.enc.EncodeEntry(ent, e.r.fields(fields))
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type account struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Mobile   string `json:"mobile"`
+}
+
+type credential struct{ token string }
+
+func (c credential) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("access_token", c.token)
+	enc.AddString("note", "call 13812345678")
+	return nil
+}
+
+func newTestRedactor(t *testing.T) *Redactor {
+	var values []ValueRule
+	for _, name := range ValueRuleNames {
+		rule, err := NewValueRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, rule)
+	}
+	keys := append(DefaultKeyRules(), KeyRule{Pattern: "card", Mask: Mask{Style: MaskHash}})
+	r, err := NewRedactor(keys, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRedactor(t *testing.T) {
+	r := newTestRedactor(t)
+	tests := []struct {
+		in, want string
+	}{
+		{"mobile 13812345678", "mobile 138****5678"},
+		{"mail alice@example.com", "mail a****@example.com"},
+		{"id 11010519491231002X", "id 110***********002X"},
+		{"id 110105194912310021", "id 110105194912310021"},
+		{"card 4111111111111111", "card 411111******1111"},
+		{"order 4111111111111112", "order 4111111111111112"},
+	}
+	for _, tt := range tests {
+		if got := r.Value(tt.in); got != tt.want {
+			t.Errorf("Value(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := r.String("App_Secret", "abc"); got != "******" {
+		t.Errorf("want key masked, got %q", got)
+	}
+	if got := r.String("card", "4111"); !strings.HasPrefix(got, "sha256:") {
+		t.Errorf("want hashed value, got %q", got)
+	}
+}
+
+func TestRedactEncoder(t *testing.T) {
+	for _, encoder := range []Encoder{JSONEncoder, ConsoleEncoder} {
+		buf := new(bytes.Buffer)
+		logger, err := New(ZapLogger,
+			WithWriter(buf),
+			WithEncoder(encoder),
+			WithEncoderCfg(NewEncoderConfig()),
+			WithRedactor(newTestRedactor(t)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logger.WithField("token", "t0k3n").With(String("email", "bob@example.com")).Infow(
+			"login from 13912345678",
+			"password", "p@ss",
+			"db_password", 1234,
+			"user", account{Name: "alice", Password: "p@ss", Mobile: "13812345678"},
+			zap.Object("credential", credential{"t0k3n"}),
+		)
+
+		out := buf.String()
+		for _, leak := range []string{"t0k3n", "p@ss", "1234", "13812345678", "13912345678", "bob@"} {
+			if strings.Contains(out, leak) {
+				t.Errorf("%v: %q leaked in %s", encoder, leak, out)
+			}
+		}
+		for _, want := range []string{"139****5678", "b**@example.com", "alice", "138****5678"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%v: want %q in %s", encoder, want, out)
+			}
+		}
+	}
+}
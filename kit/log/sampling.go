@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultSamplingTick = time.Second
+
+// SamplingConfig caps the entries written per message and level: within
+// every Tick the first Initial entries are written, then every Thereafter-th
+// one. The f variants are sampled by their format string, so messages of
+// the same template share a budget.
+type SamplingConfig struct {
+	Tick       time.Duration
+	Initial    int
+	Thereafter int
+}
+
+// samplingCore samples entries with zap's sampler. The dropped entries are
+// summarized to the unsampled core once per tick while entries are dropped,
+// and on Sync.
+type samplingCore struct {
+	zapcore.Core
+	dropped *droppedCounter
+}
+
+type droppedCounter struct {
+	tick time.Duration
+	base zapcore.Core
+	n    uint64
+	// level of the last dropped entry
+	level   int32
+	running int32
+}
+
+func newSamplingCore(core zapcore.Core, cfg *SamplingConfig) zapcore.Core {
+	tick := cfg.Tick
+	if tick <= 0 {
+		tick = defaultSamplingTick
+	}
+	dropped := &droppedCounter{tick: tick, base: core}
+	hook := zapcore.SamplerHook(func(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+		if dec&zapcore.LogDropped > 0 {
+			dropped.add(ent.Level)
+		}
+	})
+
+	return &samplingCore{
+		Core:    zapcore.NewSamplerWithOptions(core, tick, cfg.Initial, cfg.Thereafter, hook),
+		dropped: dropped,
+	}
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{
+		Core:    c.Core.With(fields),
+		dropped: c.dropped,
+	}
+}
+
+func (c *samplingCore) Sync() error {
+	c.dropped.flush()
+	return c.Core.Sync()
+}
+
+// add counts a dropped entry and starts the summary ticker if it is not
+// running.
+func (d *droppedCounter) add(lvl zapcore.Level) {
+	atomic.StoreInt32(&d.level, int32(lvl))
+	atomic.AddUint64(&d.n, 1)
+	if atomic.CompareAndSwapInt32(&d.running, 0, 1) {
+		go d.run()
+	}
+}
+
+// run writes the summary every tick, it stops after a tick without dropped
+// entries so an idle logger keeps no goroutine.
+func (d *droppedCounter) run() {
+	t := time.NewTicker(d.tick)
+	defer t.Stop()
+	for range t.C {
+		if d.flush() > 0 {
+			continue
+		}
+		atomic.StoreInt32(&d.running, 0)
+		// keep running when an entry was dropped in between and add did not
+		// start another ticker
+		if atomic.LoadUint64(&d.n) == 0 || !atomic.CompareAndSwapInt32(&d.running, 0, 1) {
+			return
+		}
+	}
+}
+
+// flush writes the summary of the entries dropped since the last one, at
+// warn or at the level of the last dropped entry when the core is capped
+// below warn, e.g. WithMaxLevel(InfoLevel).
+func (d *droppedCounter) flush() uint64 {
+	n := atomic.SwapUint64(&d.n, 0)
+	if n == 0 {
+		return 0
+	}
+	lvl := zapcore.WarnLevel
+	if !d.base.Enabled(lvl) {
+		lvl = zapcore.Level(atomic.LoadInt32(&d.level))
+	}
+	if d.base.Enabled(lvl) {
+		_ = d.base.Write(zapcore.Entry{
+			Level:   lvl,
+			Time:    time.Now(),
+			Message: fmt.Sprintf("%d messages dropped by sampling", n),
+		}, nil)
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is written by the summary ticker and read by the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func TestSampling(t *testing.T) {
+	buf := new(syncBuffer)
+	logger, err := New(ZapLogger,
+		WithWriter(buf),
+		WithEncoder(JSONEncoder),
+		WithEncoderCfg(NewEncoderConfig()),
+		WithSampling(SamplingConfig{Tick: 100 * time.Millisecond, Initial: 2, Thereafter: 3}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sampled by template, the messages differ
+	for i := 0; i < 8; i++ {
+		logger.Infof("user %d not found", i)
+	}
+	// another level has its own budget
+	logger.Warnf("user %d not found", 0)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// 1st, 2nd, 5th and 8th info, then the warn
+	if len(lines) != 5 {
+		t.Fatalf("want 5 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[2], "user 4 not found") || !strings.Contains(lines[4], `"level":"warn"`) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+
+	// written by the ticker without a later entry
+	buf.Reset()
+	time.Sleep(150 * time.Millisecond)
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "4 messages dropped by sampling") {
+		t.Fatalf("want a dropped summary, got %q", lines)
+	}
+}
+
+func TestSamplingMaxLevel(t *testing.T) {
+	buf := new(syncBuffer)
+	logger, err := New(ZapLogger,
+		WithWriter(buf),
+		WithEncoder(JSONEncoder),
+		WithEncoderCfg(NewEncoderConfig()),
+		WithMaxLevel(InfoLevel),
+		WithSampling(SamplingConfig{Tick: 100 * time.Millisecond, Initial: 1, Thereafter: 100}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		logger.Info("retry")
+	}
+	buf.Reset()
+	_ = logger.Sync()
+
+	// the warn summary would be dropped by the max level
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "2 messages dropped by sampling") || !strings.Contains(lines[0], `"level":"info"`) {
+		t.Fatalf("want a dropped summary at info, got %q", lines)
+	}
+}
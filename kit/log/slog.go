@@ -0,0 +1,409 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CallerLogger is implemented by the loggers which can log on behalf of the
+// caller at pc, so the entries logged through an adapter such as
+// SlogHandler report the caller of the adapter.
+type CallerLogger interface {
+	LogCaller(pc uintptr, lv Level, msg string, fields ...Field)
+}
+
+// LogCaller logs msg with the caller at pc if l is a CallerLogger, otherwise
+// through the w methods of l.
+func LogCaller(l Logger, pc uintptr, lv Level, msg string, fields ...Field) {
+	if cl, ok := l.(CallerLogger); ok {
+		cl.LogCaller(pc, lv, msg, fields...)
+		return
+	}
+
+	kv := make([]interface{}, len(fields))
+	for i, f := range fields {
+		kv[i] = f
+	}
+	switch {
+	case lv < InfoLevel:
+		l.Debugw(msg, kv...)
+	case lv < WarnLevel:
+		l.Infow(msg, kv...)
+	case lv < ErrorLevel:
+		l.Warnw(msg, kv...)
+	default:
+		l.Errorw(msg, kv...)
+	}
+}
+
+// SlogHandler is a slog.Handler writing through a Logger, so the libraries
+// logging with slog share its writers and encoders. The attrs of a group
+// are written with dotted keys, e.g. "req.id".
+type SlogHandler struct {
+	l      Logger
+	opts   slog.HandlerOptions
+	groups []string
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler creates a handler writing to l, opts may be nil. The level
+// and ReplaceAttr of opts are used, the source is the caller of l.
+func NewSlogHandler(l Logger, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{l: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, lv slog.Level) bool {
+	min := slog.LevelDebug
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return lv >= min
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = h.appendAttr(fields, h.groups, a)
+		return true
+	})
+	fields = append(fields, contextZapFields(ctx)...)
+	LogCaller(h.l, r.PC, levelOfSlog(r.Level), r.Message, fields...)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, a := range attrs {
+		fields = h.appendAttr(fields, h.groups, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	clone := *h
+	clone.l = h.l.With(fields...)
+	return &clone
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &clone
+}
+
+func (h *SlogHandler) appendAttr(fields []Field, groups []string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		// a group without a key is inlined
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range attrs {
+			fields = h.appendAttr(fields, groups, ga)
+		}
+		return fields
+	}
+
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	return append(fields, fieldOfSlog(key, a.Value))
+}
+
+func fieldOfSlog(key string, v slog.Value) Field {
+	switch v.Kind() {
+	case slog.KindString:
+		return zap.String(key, v.String())
+	case slog.KindInt64:
+		return zap.Int64(key, v.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(key, v.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(key, v.Float64())
+	case slog.KindBool:
+		return zap.Bool(key, v.Bool())
+	case slog.KindDuration:
+		return zap.Duration(key, v.Duration())
+	case slog.KindTime:
+		return zap.Time(key, v.Time())
+	}
+	if err, ok := v.Any().(error); ok {
+		return zap.NamedError(key, err)
+	}
+	return zap.Any(key, v.Any())
+}
+
+func levelOfSlog(lv slog.Level) Level {
+	switch {
+	case lv < slog.LevelInfo:
+		return DebugLevel
+	case lv < slog.LevelWarn:
+		return InfoLevel
+	case lv < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+func slogLevelOf(lv Level) slog.Level {
+	switch {
+	case lv < InfoLevel:
+		return slog.LevelDebug
+	case lv < WarnLevel:
+		return slog.LevelInfo
+	case lv < ErrorLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+// slogLogger is a Logger writing to a slog.Logger.
+type slogLogger struct {
+	l     *slog.Logger
+	ctx   context.Context
+	level *atomic.Int32
+}
+
+var _ Logger = (*slogLogger)(nil)
+
+// NewSlogLogger creates a Logger writing to l, e.g. to hand the logger of
+// an application to elf. Like the zap logger, panic and fatal are written
+// at error level, panic does not panic while fatal exits with status 1.
+func NewSlogLogger(l *slog.Logger) Logger {
+	level := new(atomic.Int32)
+	level.Store(int32(DebugLevel))
+	return &slogLogger{l: l, ctx: context.Background(), level: level}
+}
+
+// log must be called by the methods of the Logger, the caller is two frames
+// above.
+func (s *slogLogger) log(ctx context.Context, lv Level, msg string, fields []Field) {
+	if lv < Level(s.level.Load()) {
+		return
+	}
+	slv := slogLevelOf(lv)
+	if !s.l.Enabled(ctx, slv) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// runtime.Callers, log and the Logger method
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), slv, msg, pcs[0])
+	r.AddAttrs(slogAttrs(fields)...)
+	_ = s.l.Handler().Handle(ctx, r)
+}
+
+func slogAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			attrs = append(attrs, slog.String(f.Key, f.String))
+		case zapcore.Int64Type:
+			attrs = append(attrs, slog.Int64(f.Key, f.Integer))
+		case zapcore.BoolType:
+			attrs = append(attrs, slog.Bool(f.Key, f.Integer == 1))
+		case zapcore.ReflectType, zapcore.ErrorType:
+			attrs = append(attrs, slog.Any(f.Key, f.Interface))
+		default:
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			for k, v := range enc.Fields {
+				attrs = append(attrs, slog.Any(k, v))
+			}
+		}
+	}
+	return attrs
+}
+
+func (s *slogLogger) Debug(args ...interface{}) {
+	s.log(s.ctx, DebugLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.log(s.ctx, DebugLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Info(args ...interface{}) {
+	s.log(s.ctx, InfoLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.log(s.ctx, InfoLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Warn(args ...interface{}) {
+	s.log(s.ctx, WarnLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.log(s.ctx, WarnLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Error(args ...interface{}) {
+	s.log(s.ctx, ErrorLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.log(s.ctx, ErrorLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Trace(args ...interface{}) {
+	s.log(s.ctx, TraceLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Tracef(format string, args ...interface{}) {
+	s.log(s.ctx, TraceLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Panic(args ...interface{}) {
+	s.log(s.ctx, PanicLevel, fmt.Sprint(args...), nil)
+}
+
+func (s *slogLogger) Panicf(format string, args ...interface{}) {
+	s.log(s.ctx, PanicLevel, fmt.Sprintf(format, args...), nil)
+}
+
+func (s *slogLogger) Fatal(args ...interface{}) {
+	s.log(s.ctx, FatalLevel, fmt.Sprint(args...), nil)
+	exit(1)
+}
+
+func (s *slogLogger) Fatalf(format string, args ...interface{}) {
+	s.log(s.ctx, FatalLevel, fmt.Sprintf(format, args...), nil)
+	exit(1)
+}
+
+func (s *slogLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.log(s.ctx, DebugLevel, msg, KeysAndValues(keysAndValues...))
+}
+
+func (s *slogLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.log(s.ctx, InfoLevel, msg, KeysAndValues(keysAndValues...))
+}
+
+func (s *slogLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.log(s.ctx, WarnLevel, msg, KeysAndValues(keysAndValues...))
+}
+
+func (s *slogLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.log(s.ctx, ErrorLevel, msg, KeysAndValues(keysAndValues...))
+}
+
+func (s *slogLogger) DebugCtx(ctx context.Context, args ...interface{}) {
+	s.log(ctx, DebugLevel, fmt.Sprint(args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	s.log(ctx, DebugLevel, fmt.Sprintf(format, args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) InfoCtx(ctx context.Context, args ...interface{}) {
+	s.log(ctx, InfoLevel, fmt.Sprint(args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	s.log(ctx, InfoLevel, fmt.Sprintf(format, args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) WarnCtx(ctx context.Context, args ...interface{}) {
+	s.log(ctx, WarnLevel, fmt.Sprint(args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	s.log(ctx, WarnLevel, fmt.Sprintf(format, args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	s.log(ctx, ErrorLevel, fmt.Sprint(args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	s.log(ctx, ErrorLevel, fmt.Sprintf(format, args...), contextZapFields(ctx))
+}
+
+func (s *slogLogger) WithField(key string, value interface{}) Logger {
+	if key == "" {
+		return s
+	}
+	return s.with(slog.Any(key, value))
+}
+
+func (s *slogLogger) WithFields(fields map[string]interface{}) Logger {
+	if len(fields) == 0 {
+		return s
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	return s.with(attrs...)
+}
+
+func (s *slogLogger) With(fields ...Field) Logger {
+	if len(fields) == 0 {
+		return s
+	}
+	return s.with(slogAttrs(fields)...)
+}
+
+func (s *slogLogger) with(attrs ...slog.Attr) *slogLogger {
+	clone := *s
+	clone.l = slog.New(s.l.Handler().WithAttrs(attrs))
+	return &clone
+}
+
+// WithContext passes ctx to the handler and adds the context fields.
+func (s *slogLogger) WithContext(ctx context.Context) Logger {
+	clone := s
+	if fields := contextZapFields(ctx); len(fields) > 0 {
+		clone = s.with(slogAttrs(fields)...)
+	} else {
+		c := *s
+		clone = &c
+	}
+	clone.ctx = ctx
+	return clone
+}
+
+func (s *slogLogger) SetLogLevel(lv Level) error {
+	if _, err := zapLevelParse(lv); err != nil {
+		return err
+	}
+	s.level.Store(int32(lv))
+	return nil
+}
+
+// Sync does nothing, the slog handlers write synchronously.
+func (s *slogLogger) Sync() error {
+	return nil
+}
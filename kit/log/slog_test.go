@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger, err := New(ZapLogger,
+		WithWriter(buf),
+		WithEncoder(JSONEncoder),
+		WithEncoderCfg(NewEncoderConfig()),
+		AddCaller(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sl := slog.New(NewSlogHandler(logger, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	sl.Debug("dropped")
+	sl.With("svc", "user").WithGroup("req").Warn("slow request",
+		"id", 7,
+		slog.Group("peer", "ip", "10.0.0.1"),
+		"err", errors.New("timeout"),
+	)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("want 1 line, got %q", lines)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"level":       "warn",
+		"msg":         "slow request",
+		"svc":         "user",
+		"req.id":      float64(7),
+		"req.peer.ip": "10.0.0.1",
+		"req.err":     "timeout",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("want %s=%v, got %v", k, v, entry[k])
+		}
+	}
+	if caller, _ := entry["caller"].(string); !strings.Contains(caller, "slog_test.go") {
+		t.Errorf("want the caller of slog, got %q", caller)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true})))
+	_ = l.SetLogLevel(InfoLevel)
+
+	l.Debug("dropped")
+	l.WithField("svc", "user").With(String("id", "7")).Infow("done", "cost", 3)
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Fatalf("debug should be filtered, got %s", out)
+	}
+	for _, want := range []string{`"msg":"done"`, `"svc":"user"`, `"id":"7"`, `"cost":3`, "slog_test.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %s in %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package async provides an io.Writer writing in the background, so the
+// latency of the disk does not block the callers.
+package async
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultSize          = 8192
+	DefaultBufferSize    = 256 * 1024
+	DefaultFlushInterval = time.Second
+)
+
+// Policy decides what happens to a write when the queue is full.
+type Policy int
+
+const (
+	// Block waits until the queue has room
+	Block Policy = iota
+	// DropNewest discards the write
+	DropNewest
+	// DropOldest discards the oldest queued write to make room
+	DropOldest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropNewest:
+		return "drop_newest"
+	case DropOldest:
+		return "drop_oldest"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// ParsePolicy parses "block", "drop_newest" or "drop_oldest".
+func ParsePolicy(text string) (Policy, error) {
+	switch strings.ToLower(text) {
+	case "", "block":
+		return Block, nil
+	case "drop_newest":
+		return DropNewest, nil
+	case "drop_oldest":
+		return DropOldest, nil
+	}
+	return Block, fmt.Errorf("invalid async policy %q", text)
+}
+
+var ErrClosed = errors.New("async writer is closed")
+
+type Config struct {
+	Size          int
+	Policy        Policy
+	BufferSize    int
+	FlushInterval time.Duration
+}
+
+func NewWriterConfig() *Config {
+	return &Config{
+		Size:          DefaultSize,
+		Policy:        Block,
+		BufferSize:    DefaultBufferSize,
+		FlushInterval: DefaultFlushInterval,
+	}
+}
+
+// Stats are the counters of a Writer.
+type Stats struct {
+	Written       uint64
+	DroppedNewest uint64
+	DroppedOldest uint64
+}
+
+// Writer queues the writes in a bounded ring buffer and writes them to the
+// underlying writer from a single goroutine through a buffer, which is
+// flushed periodically, on Sync and on Close.
+type Writer struct {
+	cfg *Config
+	w   io.Writer
+	buf *bufio.Writer
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	queue   [][]byte
+	head    int
+	count   int
+	closed  bool
+
+	wake  chan struct{}
+	syncs chan chan error
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	// err is the last write error, owned by the background goroutine
+	err      error
+	closeErr error
+
+	written       uint64
+	droppedNewest uint64
+	droppedOldest uint64
+}
+
+// NewWriter starts writing to w in the background.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	cfg := NewWriterConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSize
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+
+	aw := &Writer{
+		cfg:   cfg,
+		w:     w,
+		buf:   bufio.NewWriterSize(w, cfg.BufferSize),
+		queue: make([][]byte, cfg.Size),
+		wake:  make(chan struct{}, 1),
+		syncs: make(chan chan error),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	aw.notFull = sync.NewCond(&aw.mu)
+	go aw.run()
+	return aw
+}
+
+// Write queues a copy of p, it never returns a write error of the underlying
+// writer, which is reported by Sync instead.
+func (w *Writer) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrClosed
+	}
+	for w.count == len(w.queue) {
+		switch w.cfg.Policy {
+		case DropNewest:
+			w.mu.Unlock()
+			atomic.AddUint64(&w.droppedNewest, 1)
+			return len(p), nil
+		case DropOldest:
+			w.queue[w.head] = nil
+			w.head = (w.head + 1) % len(w.queue)
+			w.count--
+			atomic.AddUint64(&w.droppedOldest, 1)
+		default:
+			w.notFull.Wait()
+			if w.closed {
+				w.mu.Unlock()
+				return 0, ErrClosed
+			}
+		}
+	}
+	w.queue[(w.head+w.count)%len(w.queue)] = b
+	w.count++
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Sync writes the queued writes, flushes the buffer and syncs the underlying
+// writer if it can.
+func (w *Writer) Sync() error {
+	ch := make(chan error, 1)
+	select {
+	case w.syncs <- ch:
+		return <-ch
+	case <-w.done:
+		return nil
+	}
+}
+
+// Close writes the queued writes, stops the background goroutine and closes
+// the underlying writer if it is an io.Closer. Writes after Close fail with
+// ErrClosed.
+func (w *Writer) Close() error {
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		close(w.quit)
+		<-w.done
+
+		if c, ok := w.w.(io.Closer); ok {
+			if err := c.Close(); err != nil && w.closeErr == nil {
+				w.closeErr = err
+			}
+		}
+	})
+	return w.closeErr
+}
+
+// Stats returns the counters of the writer.
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Written:       atomic.LoadUint64(&w.written),
+		DroppedNewest: atomic.LoadUint64(&w.droppedNewest),
+		DroppedOldest: atomic.LoadUint64(&w.droppedOldest),
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, len(w.queue))
+	for {
+		select {
+		case <-w.wake:
+			batch = w.drain(batch)
+		case <-ticker.C:
+			batch = w.drain(batch)
+			_ = w.flush()
+		case ch := <-w.syncs:
+			batch = w.drain(batch)
+			ch <- w.sync()
+		case <-w.quit:
+			w.drain(batch)
+			w.closeErr = w.sync()
+			return
+		}
+	}
+}
+
+// drain writes the queued writes to the buffer until the queue is empty.
+func (w *Writer) drain(batch [][]byte) [][]byte {
+	for {
+		w.mu.Lock()
+		if w.count == 0 {
+			w.mu.Unlock()
+			return batch
+		}
+		for ; w.count > 0; w.count-- {
+			batch = append(batch, w.queue[w.head])
+			w.queue[w.head] = nil
+			w.head = (w.head + 1) % len(w.queue)
+		}
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		for i, b := range batch {
+			if _, err := w.buf.Write(b); err != nil {
+				w.fail(err)
+			}
+			batch[i] = nil
+		}
+		atomic.AddUint64(&w.written, uint64(len(batch)))
+		batch = batch[:0]
+	}
+}
+
+func (w *Writer) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		w.fail(err)
+	}
+	err := w.err
+	w.err = nil
+	return err
+}
+
+func (w *Writer) sync() error {
+	err := w.flush()
+	if s, ok := w.w.(interface{ Sync() error }); ok {
+		if serr := s.Sync(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// fail records err and resets the buffer, whose errors are sticky.
+func (w *Writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+	w.buf.Reset(w.w)
+}
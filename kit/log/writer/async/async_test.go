@@ -0,0 +1,116 @@
+package async
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks the writes until the gate is opened.
+type gateWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gateWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.String()
+}
+
+func TestWriterClose(t *testing.T) {
+	gw := &gateWriter{gate: make(chan struct{})}
+	close(gw.gate)
+	w := NewWriter(gw, WithFlushInterval(time.Hour))
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(gw.String(), "\n"); n != 100 {
+		t.Fatalf("want 100 lines flushed on close, got %d", n)
+	}
+	if _, err := w.Write([]byte("late\n")); err != ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
+
+func TestWriterPolicy(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   string
+		stats  Stats
+	}{
+		{DropNewest, "0\n1\n2\n", Stats{Written: 3, DroppedNewest: 3}},
+		{DropOldest, "0\n4\n5\n", Stats{Written: 3, DroppedOldest: 3}},
+	}
+	for _, tt := range tests {
+		gw := &gateWriter{gate: make(chan struct{})}
+		// a buffer of one byte makes every write reach the gate
+		w := NewWriter(gw, WithSize(2), WithPolicy(tt.policy), WithBufferSize(1))
+
+		fmt.Fprintf(w, "0\n")
+		// wait until the first write is taken and blocked on the gate
+		for {
+			w.mu.Lock()
+			empty := w.count == 0
+			w.mu.Unlock()
+			if empty {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		for i := 1; i < 6; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+		}
+		close(gw.gate)
+		_ = w.Close()
+
+		if got := gw.String(); got != tt.want {
+			t.Errorf("%v: want %q, got %q", tt.policy, tt.want, got)
+		}
+		if got := w.Stats(); got != tt.stats {
+			t.Errorf("%v: want %+v, got %+v", tt.policy, tt.stats, got)
+		}
+	}
+}
+
+func TestWriterBlock(t *testing.T) {
+	gw := &gateWriter{gate: make(chan struct{})}
+	w := NewWriter(gw, WithSize(1), WithBufferSize(1))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("writes should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(gw.gate)
+	<-done
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := gw.String(); got != "0\n1\n2\n3\n4\n" {
+		t.Fatalf("unexpected output %q", got)
+	}
+	_ = w.Close()
+}
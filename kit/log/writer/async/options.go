@@ -0,0 +1,43 @@
+package async
+
+import (
+	"time"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithSize sets the number of writes the queue holds.
+func WithSize(size int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Size = size
+	})
+}
+
+// WithPolicy sets what happens to a write when the queue is full.
+func WithPolicy(policy Policy) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Policy = policy
+	})
+}
+
+// WithBufferSize sets the size of the buffer in front of the writer.
+func WithBufferSize(size int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.BufferSize = size
+	})
+}
+
+// WithFlushInterval sets how often the buffer is flushed.
+func WithFlushInterval(interval time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.FlushInterval = interval
+	})
+}
@@ -0,0 +1,272 @@
+// Package httpbatch provides an io.Writer posting the entries in batches of
+// newline delimited lines to an http endpoint.
+package httpbatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultBatchSize     = 500
+	DefaultBatchBytes    = 1 << 20
+	DefaultFlushInterval = time.Second
+	DefaultMaxRetries    = 3
+	DefaultBackoff       = 200 * time.Millisecond
+	DefaultTimeout       = 5 * time.Second
+	contentType          = "application/x-ndjson"
+)
+
+var ErrClosed = errors.New("http batch writer is closed")
+
+type Config struct {
+	Headers       map[string]string
+	BatchSize     int
+	BatchBytes    int
+	FlushInterval time.Duration
+	MaxRetries    int
+	Backoff       time.Duration
+	Timeout       time.Duration
+	Gzip          bool
+	Client        *http.Client
+}
+
+func NewWriterConfig() *Config {
+	return &Config{
+		BatchSize:     DefaultBatchSize,
+		BatchBytes:    DefaultBatchBytes,
+		FlushInterval: DefaultFlushInterval,
+		MaxRetries:    DefaultMaxRetries,
+		Backoff:       DefaultBackoff,
+		Timeout:       DefaultTimeout,
+		Client:        http.DefaultClient,
+	}
+}
+
+// Stats are the counters of a Writer, in lines.
+type Stats struct {
+	Posted  uint64
+	Dropped uint64
+}
+
+// Writer collects the writes as lines and posts them when the batch reaches
+// BatchSize lines or BatchBytes, every FlushInterval, on Sync and on Close.
+// A batch failing with a network error, 429 or 5xx is retried MaxRetries
+// times with a doubling backoff, then dropped and counted in Stats. A write
+// filling the batch posts it, so the writer is usually wrapped by an async
+// writer.
+type Writer struct {
+	url string
+	cfg *Config
+
+	posted  uint64
+	dropped uint64
+
+	mu     sync.Mutex
+	batch  bytes.Buffer
+	lines  int
+	closed bool
+
+	// sendMu keeps the batches in order
+	sendMu sync.Mutex
+
+	quit chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewWriter creates a writer posting to rawURL.
+func NewWriter(rawURL string, opts ...Option) (*Writer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid http batch url %q", rawURL)
+	}
+
+	cfg := NewWriterConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchBytes <= 0 {
+		cfg.BatchBytes = DefaultBatchBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	w := &Writer{
+		url:  rawURL,
+		cfg:  cfg,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrClosed
+	}
+	w.batch.Write(p)
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		w.batch.WriteByte('\n')
+	}
+	w.lines++
+	full := w.lines >= w.cfg.BatchSize || w.batch.Len() >= w.cfg.BatchBytes
+	w.mu.Unlock()
+
+	// p is part of the batch either way, a failed batch is dropped and
+	// counted, so the write does not fail
+	if full {
+		_ = w.flush()
+	}
+	return len(p), nil
+}
+
+// Sync posts the collected lines, it returns the error of a dropped batch.
+func (w *Writer) Sync() error {
+	return w.flush()
+}
+
+// Stats returns the counters of the writer.
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Posted:  atomic.LoadUint64(&w.posted),
+		Dropped: atomic.LoadUint64(&w.dropped),
+	}
+}
+
+// Close posts the collected lines and stops the periodic flush, writes
+// after Close fail with ErrClosed.
+func (w *Writer) Close() error {
+	var err error
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+
+		close(w.quit)
+		<-w.done
+		err = w.flush()
+	})
+	return err
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = w.flush()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+func (w *Writer) flush() error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	w.mu.Lock()
+	if w.lines == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	body := make([]byte, w.batch.Len())
+	copy(body, w.batch.Bytes())
+	lines := uint64(w.lines)
+	w.batch.Reset()
+	w.lines = 0
+	w.mu.Unlock()
+
+	if err := w.post(body); err != nil {
+		atomic.AddUint64(&w.dropped, lines)
+		return err
+	}
+	atomic.AddUint64(&w.posted, lines)
+	return nil
+}
+
+func (w *Writer) post(body []byte) error {
+	if w.cfg.Gzip {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = b.Bytes()
+	}
+
+	backoff := w.cfg.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		if retry, err = w.send(body); err == nil || !retry || attempt >= w.cfg.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send posts body once, it reports whether a failure can be retried.
+func (w *Writer) send(body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if w.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("http batch post %s: %s", w.url, resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
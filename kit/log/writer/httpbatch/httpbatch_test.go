@@ -0,0 +1,118 @@
+package httpbatch
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type collector struct {
+	mu      sync.Mutex
+	batches []string
+	// fails is the number of requests answered with 503
+	fails int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fails > 0 {
+		c.fails--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, _ := io.ReadAll(body)
+	c.batches = append(c.batches, r.Header.Get("X-Token")+":"+string(b))
+}
+
+func (c *collector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.batches...)
+}
+
+func TestWriterBatch(t *testing.T) {
+	c := &collector{fails: 2}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	w, err := NewWriter(srv.URL,
+		WithBatchSize(2),
+		WithFlushInterval(time.Hour),
+		WithRetry(2, time.Millisecond),
+		WithGzip(true),
+		WithHeaders(map[string]string{"X-Token": "t"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second line fills the batch, posted after two retries
+	for _, line := range []string{`{"n":1}`, "{\"n\":2}\n", `{"n":3}`} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := c.get(); len(got) != 1 || got[0] != "t:{\"n\":1}\n{\"n\":2}\n" {
+		t.Fatalf("unexpected batches %q", got)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.get(); len(got) != 2 || got[1] != "t:{\"n\":3}\n" {
+		t.Fatalf("the last line should be posted on close, got %q", got)
+	}
+	if _, err := w.Write([]byte("late")); err != ErrClosed {
+		t.Fatalf("want ErrClosed, got %v", err)
+	}
+}
+
+func TestWriterGiveUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(srv.URL, WithFlushInterval(time.Hour), WithRetry(3, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.Write([]byte("line"))
+	// 400 is not retried, or the backoff of an hour would block
+	if err := w.Sync(); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("want a 400 error, got %v", err)
+	}
+
+	// a write filling the batch does not fail, the batch is counted
+	full, err := NewWriter(srv.URL, WithBatchSize(1), WithFlushInterval(time.Hour), WithRetry(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer full.Close()
+	if n, err := full.Write([]byte("line")); n != 4 || err != nil {
+		t.Fatalf("write returned %d, %v", n, err)
+	}
+	if s := w.Stats(); s.Dropped != 1 || s.Posted != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s := full.Stats(); s.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
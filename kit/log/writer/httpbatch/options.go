@@ -0,0 +1,73 @@
+package httpbatch
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithHeaders sets headers of the requests, e.g. an authorization.
+func WithHeaders(headers map[string]string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Headers = headers
+	})
+}
+
+// WithBatchSize sets the number of lines posting a batch.
+func WithBatchSize(size int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.BatchSize = size
+	})
+}
+
+// WithBatchBytes sets the size in bytes posting a batch.
+func WithBatchBytes(size int) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.BatchBytes = size
+	})
+}
+
+// WithFlushInterval sets how often the collected lines are posted.
+func WithFlushInterval(interval time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.FlushInterval = interval
+	})
+}
+
+// WithRetry sets the number of retries of a batch and the first wait.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MaxRetries = maxRetries
+		cfg.Backoff = backoff
+	})
+}
+
+// WithTimeout sets the timeout of a request.
+func WithTimeout(timeout time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Timeout = timeout
+	})
+}
+
+// WithGzip compresses the batches.
+func WithGzip(enable bool) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Gzip = enable
+	})
+}
+
+// WithClient sets the http client.
+func WithClient(client *http.Client) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Client = client
+	})
+}
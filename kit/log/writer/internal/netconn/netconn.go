@@ -0,0 +1,132 @@
+// Package netconn provides a connection which redials lazily, backing off
+// while the peer is unreachable.
+package netconn
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDialTimeout  = 3 * time.Second
+	DefaultWriteTimeout = 3 * time.Second
+	DefaultMinBackoff   = 100 * time.Millisecond
+	DefaultMaxBackoff   = 30 * time.Second
+)
+
+// ErrUnavailable is returned while waiting to redial.
+var ErrUnavailable = errors.New("connection unavailable, waiting to reconnect")
+
+type Config struct {
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Conn writes to network and addr, dialing on the first write and after a
+// failure. A failed write is retried once on a new connection, then the
+// writes fail with ErrUnavailable until the backoff, doubled on every
+// failure, has passed.
+type Conn struct {
+	network string
+	addr    string
+	cfg     Config
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+	closed  bool
+}
+
+func New(network, addr string, cfg Config) *Conn {
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = DefaultDialTimeout
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Conn{network: network, addr: addr, cfg: cfg}
+}
+
+// Stream reports whether the network preserves no message boundaries.
+func Stream(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	}
+	return false
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err := c.dial(); err != nil {
+				return 0, err
+			}
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+		var n int
+		if n, err = c.conn.Write(b); err == nil {
+			return n, nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	c.fail()
+	return 0, err
+}
+
+func (c *Conn) dial() error {
+	if time.Now().Before(c.retryAt) {
+		return ErrUnavailable
+	}
+	conn, err := net.DialTimeout(c.network, c.addr, c.cfg.DialTimeout)
+	if err != nil {
+		c.fail()
+		return err
+	}
+	c.conn = conn
+	c.backoff = 0
+	return nil
+}
+
+func (c *Conn) fail() {
+	if c.backoff == 0 {
+		c.backoff = c.cfg.MinBackoff
+	} else if c.backoff *= 2; c.backoff > c.cfg.MaxBackoff {
+		c.backoff = c.cfg.MaxBackoff
+	}
+	c.retryAt = time.Now().Add(c.backoff)
+}
+
+// Close closes the connection, writes after Close fail.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,124 @@
+package rotatelogs
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Gzip is a Compressor writing ".gz" files. There is no
+// zstd in the standard library, a zstd Compressor can be
+// provided with the package of your choice.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Ext() string {
+	return ".gz"
+}
+
+func (gzipCompressor) Compress(dst io.Writer, src io.Reader) error {
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// archive runs in the background: it waits for the writes
+// still in flight on the previous file, closes it, then
+// compresses and moves it before notifying the handler.
+func (rl *RotateLogs) archive(prev *file, prevFn, current string) {
+	defer rl.archiving.Done()
+
+	prev.writes.Wait()
+	prev.Close()
+
+	archived := prevFn
+	var err error
+	if rl.compressor != nil {
+		ext := rl.compressor.Ext()
+		dst := freeName(prevFn+ext, ext, func(name string) bool {
+			return fileExists(name) ||
+				rl.archiveDir != "" && fileExists(filepath.Join(rl.archiveDir, filepath.Base(name)))
+		})
+		if archived, err = compressFile(rl.compressor, prevFn, dst); err != nil {
+			err = fmt.Errorf("failed to compress %s: %s", prevFn, err.Error())
+			archived = prevFn
+		}
+	}
+	if err == nil && rl.archiveDir != "" {
+		ext := ""
+		if archived != prevFn {
+			ext = rl.compressor.Ext()
+		}
+		dst := freeName(filepath.Join(rl.archiveDir, filepath.Base(archived)), ext, fileExists)
+		if err = os.Rename(archived, dst); err != nil {
+			err = fmt.Errorf("failed to archive %s: %s", archived, err.Error())
+		} else {
+			archived = dst
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+
+	if rl.handler != nil {
+		rl.handler.Handle(&FileRotatedEvent{
+			prev:     prevFn,
+			current:  current,
+			archived: archived,
+			err:      err,
+		})
+	}
+}
+
+// freeName returns name, or name with the first ".N" inserted before ext
+// which is not taken, so a rotated file never replaces another one.
+func freeName(name, ext string, taken func(string) bool) string {
+	stem := strings.TrimSuffix(name, ext)
+	free := name
+	for n := 1; taken(free); n++ {
+		free = fmt.Sprintf("%s.%d%s", stem, n, ext)
+	}
+	return free
+}
+
+// compressFile replaces filename with its compressed copy dst.
+func compressFile(c Compressor, filename, dst string) (string, error) {
+	src, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	tmp := dst + `_tmp`
+	fh, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return "", err
+	}
+	if err := c.Compress(fh, src); err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := fh.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	// keep the mod time, the purge by age relies on it
+	_ = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return dst, os.Remove(filename)
+}
@@ -0,0 +1,59 @@
+package rotatelogs
+
+// Handler is notified of the events of RotateLogs.
+type Handler interface {
+	Handle(Event)
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(Event)
+
+func (h HandlerFunc) Handle(e Event) {
+	h(e)
+}
+
+type EventType int
+
+const (
+	InvalidEventType EventType = iota
+	FileRotatedEventType
+)
+
+type Event interface {
+	Type() EventType
+}
+
+// FileRotatedEvent is sent after a rotation, e.g. to upload
+// or checksum the archived file.
+type FileRotatedEvent struct {
+	prev     string
+	current  string
+	archived string
+	err      error
+}
+
+func (e *FileRotatedEvent) Type() EventType {
+	return FileRotatedEventType
+}
+
+// PreviousFile returns the file name before the rotation.
+func (e *FileRotatedEvent) PreviousFile() string {
+	return e.prev
+}
+
+// CurrentFile returns the file name written to after the rotation.
+func (e *FileRotatedEvent) CurrentFile() string {
+	return e.current
+}
+
+// ArchivedFile returns the path of the previous file after it is
+// compressed and moved to the archive dir, it is the previous file
+// when neither is set or they failed.
+func (e *FileRotatedEvent) ArchivedFile() string {
+	return e.archived
+}
+
+// Err returns the error of compressing or archiving the previous file.
+func (e *FileRotatedEvent) Err() error {
+	return e.err
+}
@@ -1,6 +1,7 @@
 package rotatelogs
 
 import (
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -11,14 +12,22 @@ import (
 // RotateLogs represents a log file that gets
 // automatically rotated as you write to it.
 type RotateLogs struct {
+	archiveDir    string
+	archiving     sync.WaitGroup
 	clock         Clock
+	closed        bool
+	compressor    Compressor
 	curFn         string
+	curBaseFn     string
+	curSize       int64
 	globPattern   string
 	generation    int
+	handler       Handler
 	linkName      string
 	maxAge        time.Duration
+	maxSize       int64
 	mutex         sync.RWMutex
-	outFh         *os.File
+	outFh         *file
 	pattern       *strftime.Strftime
 	rotationTime  time.Duration
 	rotationCount uint
@@ -45,3 +54,17 @@ type Option interface {
 	Name() string
 	Value() interface{}
 }
+
+// Compressor compresses the rotated files, files with the
+// suffix Ext are regarded as compressed when purging.
+type Compressor interface {
+	Ext() string
+	Compress(dst io.Writer, src io.Reader) error
+}
+
+// file counts the writes in flight, so that a rotated
+// file is only closed and archived once they are done.
+type file struct {
+	*os.File
+	writes sync.WaitGroup
+}
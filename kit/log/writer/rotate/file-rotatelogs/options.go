@@ -12,6 +12,10 @@ const (
 	optkeyMaxAge        = "max-age"
 	optkeyRotationTime  = "rotation-time"
 	optkeyRotationCount = "rotation-count"
+	optkeyMaxSize       = "max-size"
+	optkeyCompressor    = "compressor"
+	optkeyArchiveDir    = "archive-dir"
+	optkeyHandler       = "handler"
 )
 
 // WithClock creates a new Option that sets a clock
@@ -64,3 +68,33 @@ func WithRotationTime(d time.Duration) Option {
 func WithRotationCount(n uint) Option {
 	return option.New(optkeyRotationCount, n)
 }
+
+// WithMaxSize creates a new Option that sets the size
+// in bytes after which the file rolls over to a
+// generational file such as "foo.1", "foo.2" within
+// the same rotation time.
+func WithMaxSize(size int64) Option {
+	return option.New(optkeyMaxSize, size)
+}
+
+// WithCompressor creates a new Option that compresses
+// the files in the background once they are no longer
+// written to, e.g. rotatelogs.Gzip.
+func WithCompressor(c Compressor) Option {
+	return option.New(optkeyCompressor, c)
+}
+
+// WithArchiveDir creates a new Option that moves the
+// rotated files into dir. The dir should be on the same
+// file system as the log files. The archived files are
+// purged as well.
+func WithArchiveDir(dir string) Option {
+	return option.New(optkeyArchiveDir, dir)
+}
+
+// WithHandler creates a new Option that sets a handler
+// notified in the background after each rotation, once
+// the previous file is compressed and archived.
+func WithHandler(h Handler) Option {
+	return option.New(optkeyHandler, h)
+}
@@ -6,10 +6,10 @@ package rotatelogs
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -39,6 +39,10 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 	var rotationCount uint
 	var linkName string
 	var maxAge time.Duration
+	var maxSize int64
+	var compressor Compressor
+	var archiveDir string
+	var handler Handler
 
 	for _, o := range options {
 		switch o.Name() {
@@ -58,6 +62,17 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 			}
 		case optkeyRotationCount:
 			rotationCount = o.Value().(uint)
+		case optkeyMaxSize:
+			maxSize = o.Value().(int64)
+			if maxSize < 0 {
+				maxSize = 0
+			}
+		case optkeyCompressor:
+			compressor = o.Value().(Compressor)
+		case optkeyArchiveDir:
+			archiveDir = o.Value().(string)
+		case optkeyHandler:
+			handler = o.Value().(Handler)
 		}
 	}
 
@@ -70,11 +85,21 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 		maxAge = 7 * 24 * time.Hour
 	}
 
+	if archiveDir != "" {
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create archive dir: %s", err.Error())
+		}
+	}
+
 	return &RotateLogs{
+		archiveDir:    archiveDir,
 		clock:         clock,
+		compressor:    compressor,
 		globPattern:   globPattern,
+		handler:       handler,
 		linkName:      linkName,
 		maxAge:        maxAge,
+		maxSize:       maxSize,
 		pattern:       pattern,
 		rotationTime:  rotationTime,
 		rotationCount: rotationCount,
@@ -112,26 +137,49 @@ func (rl *RotateLogs) genFilename() string {
 func (rl *RotateLogs) Write(p []byte) (n int, err error) {
 	// Guard against concurrent writes
 	rl.mutex.Lock()
+	if rl.closed {
+		// checked first, a size rollover would open a new file
+		rl.mutex.Unlock()
+		return 0, os.ErrClosed
+	}
 
 	out, err := rl.getWriterNolock(false, false)
+	if err == nil && rl.maxSize > 0 && rl.curSize > 0 && rl.curSize+int64(len(p)) > rl.maxSize {
+		// roll over to the next generation of the same time
+		out, err = rl.getWriterNolock(false, true)
+	}
 	if err != nil {
 		rl.mutex.Unlock()
 		return 0, fmt.Errorf("failed to acquite target io.Writer: %s", err.Error())
 	}
+	if out == nil {
+		rl.mutex.Unlock()
+		return 0, os.ErrClosed
+	}
+	// the size is counted before writing, so no stat is needed per write
+	rl.curSize += int64(len(p))
+	out.writes.Add(1)
 	rl.mutex.Unlock()
 
+	defer out.writes.Done()
 	return out.Write(p)
 }
 
 // must be locked during this operation
-func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames bool) (io.Writer, error) {
+func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames bool) (*file, error) {
 	generation := rl.generation
 
 	// This filename contains the name of the "NEW" filename
 	// to log to, which may be newer than rl.currentFilename
 	filename := rl.genFilename()
-	if rl.curFn != filename {
+	baseFilename := filename
+	if rl.curBaseFn != filename {
 		generation = 0
+		// after a restart within the same rotation time the file is
+		// appended to, unless it is compressed or archived already
+		if _, err := os.Stat(filename); err != nil && rl.taken(filename) {
+			filename, generation = rl.nextGeneration(baseFilename, generation)
+		}
 	} else {
 		if !useGenerationalNames {
 			// nothing to do
@@ -141,14 +189,7 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 		// instead of just using the regular strftime pattern, we
 		// create a new file name using generational names such as
 		// "foo.1", "foo.2", "foo.3", etc
-		for {
-			generation++
-			name := fmt.Sprintf("%s.%d", filename, generation)
-			if _, err := os.Stat(name); err != nil {
-				filename = name
-				break
-			}
-		}
+		filename, generation = rl.nextGeneration(filename, generation)
 	}
 
 	// if we got here, then we need to create a file
@@ -157,6 +198,11 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 		return nil, fmt.Errorf("failed to open file %s: %s", rl.pattern, err)
 	}
 
+	var size int64
+	if fi, err := fh.Stat(); err == nil {
+		size = fi.Size()
+	}
+
 	if err := rl.rotate_nolock(filename); err != nil {
 		err = fmt.Errorf("failed to rotate: %s", err.Error())
 		if bailOnRotateFail {
@@ -172,11 +218,53 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 	// GC will close unused file descriptor.
 	// runtime.SetFinalizer(fd, (*netFD).Close).
 	// rl.outFh.Close()
-	rl.outFh = fh
+	prev, prevFn := rl.outFh, rl.curFn
+	rl.outFh = &file{File: fh}
 	rl.curFn = filename
+	rl.curBaseFn = baseFilename
+	rl.curSize = size
 	rl.generation = generation
 
-	return fh, nil
+	if prev != nil && (rl.compressor != nil || rl.archiveDir != "" || rl.handler != nil) {
+		rl.archiving.Add(1)
+		go rl.archive(prev, prevFn, filename)
+	}
+	return rl.outFh, nil
+}
+
+// nextGeneration returns the first generation of filename after generation
+// which is not taken.
+func (rl *RotateLogs) nextGeneration(filename string, generation int) (string, int) {
+	for {
+		generation++
+		name := fmt.Sprintf("%s.%d", filename, generation)
+		if !rl.taken(name) {
+			return name, generation
+		}
+	}
+}
+
+// taken reports whether name, or its compressed copy, exists next to it or
+// in the archive dir, a new file must not replace them once rotated.
+func (rl *RotateLogs) taken(name string) bool {
+	names := []string{name}
+	if rl.compressor != nil {
+		names = append(names, name+rl.compressor.Ext())
+	}
+	for _, n := range names {
+		if fileExists(n) {
+			return true
+		}
+		if rl.archiveDir != "" && fileExists(filepath.Join(rl.archiveDir, filepath.Base(n))) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
 }
 
 // CurrentFileName returns the current file name that
@@ -218,12 +306,45 @@ func (g *cleanupGuard) Run() {
 func (rl *RotateLogs) Rotate() error {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
+	if rl.closed {
+		return os.ErrClosed
+	}
 	if _, err := rl.getWriterNolock(true, true); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Reopen closes the current file and opens it again by the same
+// name, so the file can be moved away by an external tool such as
+// logrotate without copytruncate. It is safe under concurrent writes,
+// the writes in flight finish on the previous file.
+func (rl *RotateLogs) Reopen() error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	if rl.outFh == nil {
+		return nil
+	}
+
+	fh, err := os.OpenFile(rl.curFn, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen file %s: %s", rl.curFn, err)
+	}
+	var size int64
+	if fi, err := fh.Stat(); err == nil {
+		size = fi.Size()
+	}
+
+	prev := rl.outFh
+	rl.outFh = &file{File: fh}
+	rl.curSize = size
+	go func() {
+		prev.writes.Wait()
+		prev.Close()
+	}()
+	return nil
+}
+
 func (rl *RotateLogs) rotate_nolock(filename string) error {
 	lockfn := filename + `_lock`
 	fh, err := os.OpenFile(lockfn, os.O_CREATE|os.O_EXCL, 0644)
@@ -254,7 +375,7 @@ func (rl *RotateLogs) rotate_nolock(filename string) error {
 		return fmt.Errorf("panic: maxAge and rotationCount are both set")
 	}
 
-	matches, err := filepath.Glob(rl.globPattern)
+	matches, err := rl.globNolock()
 	if err != nil {
 		return err
 	}
@@ -263,7 +384,7 @@ func (rl *RotateLogs) rotate_nolock(filename string) error {
 	var toUnlink []string
 	for _, path := range matches {
 		// Ignore lock files
-		if strings.HasSuffix(path, "_lock") || strings.HasSuffix(path, "_symlink") {
+		if strings.HasSuffix(path, "_lock") || strings.HasSuffix(path, "_symlink") || strings.HasSuffix(path, "_tmp") {
 			continue
 		}
 
@@ -311,13 +432,51 @@ func (rl *RotateLogs) rotate_nolock(filename string) error {
 	return nil
 }
 
+// globNolock lists the log files including the compressed
+// and archived ones.
+func (rl *RotateLogs) globNolock() ([]string, error) {
+	patterns := []string{rl.globPattern}
+	if rl.compressor != nil {
+		patterns = append(patterns, rl.globPattern+rl.compressor.Ext())
+	}
+	if rl.archiveDir != "" {
+		for _, pattern := range patterns {
+			patterns = append(patterns, filepath.Join(rl.archiveDir, filepath.Base(pattern)))
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var matches []string
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range files {
+			if _, ok := seen[fn]; ok {
+				continue
+			}
+			seen[fn] = struct{}{}
+			matches = append(matches, fn)
+		}
+	}
+	// the names sort by time across the dirs, the oldest first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return filepath.Base(matches[i]) < filepath.Base(matches[j])
+	})
+	return matches, nil
+}
+
 // Close satisfies the io.Closer interface. You must
 // call this method if you performed any writes to
-// the object.
+// the object. It waits for the rotated files being
+// compressed and archived.
 func (rl *RotateLogs) Close() error {
+	defer rl.archiving.Wait()
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
+	rl.closed = true
 	if rl.outFh == nil {
 		return nil
 	}
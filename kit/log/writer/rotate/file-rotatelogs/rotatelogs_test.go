@@ -0,0 +1,369 @@
+package rotatelogs
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	rl, err := New(
+		filepath.Join(dir, "app.log.%Y%m%d%H"),
+		WithClock(clock),
+		WithRotationTime(time.Hour),
+		WithMaxSize(10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	line := []byte("12345678\n")
+	base := filepath.Join(dir, "app.log.2024010110")
+	for i, want := range []string{base, base + ".1", base + ".2", base + ".3"} {
+		if _, err := rl.Write(line); err != nil {
+			t.Fatal(err)
+		}
+		if got := rl.CurrentFileName(); got != want {
+			t.Fatalf("write %d: file %s, want %s", i, got, want)
+		}
+	}
+
+	// a write larger than the max size still goes to a single file
+	if _, err := rl.Write([]byte(strings.Repeat("x", 20))); err != nil {
+		t.Fatal(err)
+	}
+	if got := rl.CurrentFileName(); got != base+".4" {
+		t.Fatalf("file %s, want %s", got, base+".4")
+	}
+
+	// the next rotation time starts over with the base name
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := rl.Write(line); err != nil {
+		t.Fatal(err)
+	}
+	next := filepath.Join(dir, "app.log.2024010111")
+	if got := rl.CurrentFileName(); got != next {
+		t.Fatalf("file %s, want %s", got, next)
+	}
+
+	for _, fn := range []string{base, base + ".1", base + ".2", base + ".3"} {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != string(line) {
+			t.Fatalf("%s: %q", fn, b)
+		}
+	}
+}
+
+func TestMaxSizeReopen(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	pattern := filepath.Join(dir, "app.log.%Y%m%d%H")
+	base := filepath.Join(dir, "app.log.2024010110")
+	if err := os.WriteFile(base, []byte("123456789\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rl, err := New(pattern, WithClock(clock), WithRotationTime(time.Hour), WithMaxSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	// the size of the existing file is taken into account
+	if _, err := rl.Write([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := rl.CurrentFileName(); got != base+".1" {
+		t.Fatalf("file %s, want %s", got, base+".1")
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	rl, err := New(
+		filepath.Join(dir, "app.log.%Y%m%d%H"),
+		WithClock(clock),
+		WithRotationTime(time.Hour),
+		WithMaxSize(10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rl.Write([]byte("12345678\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the write would roll over to a new generation
+	if _, err := rl.Write([]byte("12345678\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("want os.ErrClosed, got %v", err)
+	}
+	if err := rl.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("want os.ErrClosed, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "app.log.*")); len(files) != 1 {
+		t.Fatalf("want a single file, got %v", files)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	archiveDir := filepath.Join(dir, "archive")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+
+	events := make(chan *FileRotatedEvent, 2)
+	rl, err := New(
+		filepath.Join(dir, "app.log.%Y%m%d%H"),
+		WithClock(clock),
+		WithRotationTime(time.Hour),
+		WithCompressor(Gzip),
+		WithArchiveDir(archiveDir),
+		WithHandler(HandlerFunc(func(e Event) {
+			events <- e.(*FileRotatedEvent)
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := []byte("hello\n")
+	if _, err := rl.Write(line); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := rl.Write(line); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := <-events
+	prev := filepath.Join(dir, "app.log.2024010110")
+	archived := filepath.Join(archiveDir, "app.log.2024010110.gz")
+	if e.PreviousFile() != prev || e.CurrentFile() != filepath.Join(dir, "app.log.2024010111") ||
+		e.ArchivedFile() != archived || e.Err() != nil {
+		t.Fatalf("event %+v", e)
+	}
+	if _, err := os.Stat(prev); !os.IsNotExist(err) {
+		t.Fatalf("%s is not removed: %v", prev, err)
+	}
+
+	fh, err := os.Open(archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	zr, err := gzip.NewReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(line) {
+		t.Fatalf("archived %q", b)
+	}
+
+	matches, err := rl.globNolock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0] != archived {
+		t.Fatalf("glob %v", matches)
+	}
+}
+
+func TestArchiveRestart(t *testing.T) {
+	dir := t.TempDir()
+	archiveDir := filepath.Join(dir, "archive")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	run := func(line string) {
+		rl, err := New(
+			filepath.Join(dir, "app.log.%Y%m%d%H"),
+			WithClock(clock),
+			WithRotationTime(time.Hour),
+			WithMaxSize(10),
+			WithCompressor(Gzip),
+			WithArchiveDir(archiveDir),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the second write rolls over, the first file is archived
+		for i := 0; i < 2; i++ {
+			if _, err := rl.Write([]byte(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rl.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// restarted within the same rotation time
+	run("first\n12\n")
+	run("second\n1\n")
+
+	base := filepath.Join(archiveDir, "app.log.2024010110")
+	for fn, want := range map[string]string{base + ".gz": "first\n12\n", base + ".2.gz": "second\n1\n"} {
+		fh, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("%s: %q, want %q", fn, b, want)
+		}
+	}
+	// the files still written to are left in place
+	for _, fn := range []string{"app.log.2024010110.1", "app.log.2024010110.3"} {
+		if _, err := os.Stat(filepath.Join(dir, fn)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a name taken anyway gets the next free one
+	if got := freeName(base+".gz", ".gz", fileExists); got != base+".1.gz" {
+		t.Fatalf("free name %s", got)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	rl, err := New(filepath.Join(dir, "app.log.%Y%m%d"), WithRotationTime(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	line := []byte("hello\n")
+	if _, err := rl.Write(line); err != nil {
+		t.Fatal(err)
+	}
+	fn := rl.CurrentFileName()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := rl.Write(line); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := rl.Reopen(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	// moved away by logrotate
+	if err := os.Rename(fn, fn+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rl.Write(line); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(fn + ".old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "hello"); n != 401 {
+		t.Fatalf("%d lines in the moved file", n)
+	}
+	b, err = os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(line) {
+		t.Fatalf("reopened file %q", b)
+	}
+}
+
+func TestGenFilenameDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{}
+	rl, err := New("app.log.%Y%m%d%H", WithClock(clock), WithRotationTime(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := New("app.log.%Y%m%d", WithClock(clock), WithRotationTime(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		now   time.Time
+		hour  string
+		daily string
+	}{
+		// spring forward, 02:00 becomes 03:00
+		{time.Date(2024, 3, 10, 1, 59, 0, 0, loc), "app.log.2024031001", "app.log.20240310"},
+		{time.Date(2024, 3, 10, 1, 59, 0, 0, loc).Add(time.Minute), "app.log.2024031003", "app.log.20240310"},
+		{time.Date(2024, 3, 10, 23, 30, 0, 0, loc), "app.log.2024031023", "app.log.20240310"},
+		{time.Date(2024, 3, 11, 0, 0, 0, 0, loc), "app.log.2024031100", "app.log.20240311"},
+		// fall back, 01:00 to 02:00 happens twice and is written to one file
+		{time.Date(2024, 11, 3, 0, 59, 0, 0, loc), "app.log.2024110300", "app.log.20241103"},
+		{time.Date(2024, 11, 3, 1, 30, 0, 0, loc), "app.log.2024110301", "app.log.20241103"},
+		{time.Date(2024, 11, 3, 1, 30, 0, 0, loc).Add(time.Hour), "app.log.2024110301", "app.log.20241103"},
+		{time.Date(2024, 11, 3, 1, 30, 0, 0, loc).Add(2 * time.Hour), "app.log.2024110302", "app.log.20241103"},
+		{time.Date(2024, 11, 3, 23, 59, 0, 0, loc), "app.log.2024110323", "app.log.20241103"},
+		// the same instant in UTC
+		{time.Date(2024, 11, 3, 23, 59, 0, 0, loc).UTC(), "app.log.2024110404", "app.log.20241104"},
+	}
+	for _, tt := range tests {
+		clock.now = tt.now
+		if got := rl.genFilename(); got != tt.hour {
+			t.Errorf("%s: hourly %s, want %s", tt.now, got, tt.hour)
+		}
+		if got := daily.genFilename(); got != tt.daily {
+			t.Errorf("%s: daily %s, want %s", tt.now, got, tt.daily)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package rotate
 import (
 	"os"
 	"time"
+
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
 )
 
 type Option interface {
@@ -73,3 +75,31 @@ func WithLocation(loc *time.Location) Option {
 		}
 	})
 }
+
+// sets the size in bytes after which the file rolls over to the next generation.
+func WithMaxSize(MaxSize int64) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MaxSize = MaxSize
+	})
+}
+
+// compresses the rotated files in the background, e.g. rotatelogs.Gzip.
+func WithCompressor(Compressor rotatelogs.Compressor) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Compressor = Compressor
+	})
+}
+
+// moves the rotated files into the dir, which should be on the same file system.
+func WithArchiveDir(ArchiveDir string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.ArchiveDir = ArchiveDir
+	})
+}
+
+// sets the handler notified after each rotation, e.g. to upload the archived file.
+func WithHandler(Handler rotatelogs.Handler) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Handler = Handler
+	})
+}
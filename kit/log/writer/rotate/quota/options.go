@@ -0,0 +1,65 @@
+package quota
+
+import (
+	"time"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithMaxBytes sets the total size of the files in the dir tree, 0 disables.
+func WithMaxBytes(size int64) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MaxBytes = size
+	})
+}
+
+// WithMinFree sets the free space of the file system to keep, 0 disables.
+func WithMinFree(size int64) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MinFree = size
+	})
+}
+
+// WithInterval sets how often the dir tree is checked.
+func WithInterval(interval time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Interval = interval
+	})
+}
+
+// WithDirs adds dir trees checked together with the dir, e.g. the archive
+// dir, the dirs within the dir are ignored.
+func WithDirs(dirs ...string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Dirs = append(cfg.Dirs, dirs...)
+	})
+}
+
+// WithSkipDirs sets the names of the sub dirs never scanned.
+func WithSkipDirs(names ...string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.SkipDirs = names
+	})
+}
+
+// WithOnRemove sets the function reporting the removed files.
+func WithOnRemove(fn func(path string, size int64, reason string)) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.OnRemove = fn
+	})
+}
+
+// WithOnError sets the function reporting the failed checks.
+func WithOnError(fn func(err error)) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.OnError = fn
+	})
+}
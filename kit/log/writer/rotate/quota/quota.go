@@ -0,0 +1,256 @@
+// Package quota removes the oldest rotated log files of a dir tree, shared by
+// all the rotating writers in it, when the total size exceeds a limit or the
+// free space of the file system drops below a threshold.
+package quota
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Minute
+
+	ReasonMaxBytes = "max_bytes"
+	ReasonMinFree  = "min_free"
+)
+
+type Config struct {
+	MaxBytes int64
+	MinFree  int64
+	Interval time.Duration
+	// Dirs are checked together with the dir, e.g. the archive dir
+	Dirs []string
+	// SkipDirs are the names of the sub dirs never scanned
+	SkipDirs []string
+	OnRemove func(path string, size int64, reason string)
+	OnError  func(err error)
+}
+
+func NewConfig() *Config {
+	return &Config{
+		Interval: DefaultInterval,
+	}
+}
+
+// Quota checks the dir tree every Interval. The files being written, which
+// are the targets of the symlinks created by the rotating writers, the
+// hidden files and the lock files of the writers are never removed. The
+// temporary files of the writers being compressed are neither counted.
+type Quota struct {
+	dir string
+	cfg *Config
+
+	// mu serializes the checks
+	mu sync.Mutex
+
+	quit chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// New starts checking the dir tree.
+func New(dir string, opts ...Option) (*Quota, error) {
+	cfg := NewConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	if cfg.MaxBytes <= 0 && cfg.MinFree <= 0 {
+		return nil, fmt.Errorf("quota of %s needs max bytes or min free", dir)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.MinFree > 0 {
+		if _, err := freeSpace(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	q := &Quota{
+		dir:  dir,
+		cfg:  cfg,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go q.run()
+	return q, nil
+}
+
+func (q *Quota) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := q.Check(); err != nil && q.cfg.OnError != nil {
+			q.cfg.OnError(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// Close stops checking the dir tree.
+func (q *Quota) Close() error {
+	q.once.Do(func() {
+		close(q.quit)
+		<-q.done
+	})
+	return nil
+}
+
+// Check removes the oldest rotated files until the dir tree is within the
+// quota, it is run every Interval.
+func (q *Quota) Check() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	files, total, err := q.scan()
+	if err != nil {
+		return err
+	}
+	var free int64
+	if q.cfg.MinFree > 0 {
+		if free, err = freeSpace(q.dir); err != nil {
+			return err
+		}
+	}
+
+	// the oldest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		var reason string
+		switch {
+		case q.cfg.MaxBytes > 0 && total > q.cfg.MaxBytes:
+			reason = ReasonMaxBytes
+		case q.cfg.MinFree > 0 && free < q.cfg.MinFree:
+			reason = ReasonMinFree
+		default:
+			return nil
+		}
+		if err := os.Remove(f.path); err != nil {
+			if os.IsNotExist(err) {
+				// purged by the writer meanwhile
+				continue
+			}
+			return err
+		}
+		total -= f.size
+		free += f.size
+		if q.cfg.OnRemove != nil {
+			q.cfg.OnRemove(f.path, f.size, reason)
+		}
+	}
+	return nil
+}
+
+// scan lists the removable files and the total size of the dir trees.
+func (q *Quota) scan() ([]logFile, int64, error) {
+	var (
+		files   []logFile
+		total   int64
+		current = make(map[string]struct{})
+	)
+	var root string
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && path != root && q.skipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if target, err := filepath.EvalSymlinks(path); err == nil {
+				current[target] = struct{}{}
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasSuffix(name, "_tmp") {
+			// being compressed, renamed once done
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			// removed meanwhile
+			return nil
+		}
+		total += fi.Size()
+
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "_lock") ||
+			strings.HasSuffix(name, "_symlink") {
+			return nil
+		}
+		files = append(files, logFile{path: path, size: fi.Size(), modTime: fi.ModTime()})
+		return nil
+	}
+	for _, root = range q.dirs() {
+		if err := filepath.WalkDir(root, walk); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	removable := files[:0]
+	for _, f := range files {
+		target, err := filepath.EvalSymlinks(f.path)
+		if err != nil {
+			continue
+		}
+		if _, ok := current[target]; !ok {
+			removable = append(removable, f)
+		}
+	}
+	return removable, total, nil
+}
+
+func (q *Quota) skipDir(name string) bool {
+	for _, n := range q.cfg.SkipDirs {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dirs returns the dir and the other dirs not within it.
+func (q *Quota) dirs() []string {
+	dirs := []string{q.dir}
+	root, err := filepath.Abs(q.dir)
+	if err != nil {
+		return append(dirs, q.cfg.Dirs...)
+	}
+	for _, dir := range q.cfg.Dirs {
+		abs, err := filepath.Abs(dir)
+		if err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
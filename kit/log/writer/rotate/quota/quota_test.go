@@ -0,0 +1,149 @@
+package quota
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"info/app.log.1":  5 * time.Hour,
+		"info/app.log.2":  3 * time.Hour,
+		"info/app.log.3":  0,
+		"error/app.log.1": 4 * time.Hour,
+		"error/app.log.2": 6 * time.Hour, // the current file of error
+		"error/app.log.3": 2 * time.Hour,
+	}
+	for fn, age := range files {
+		writeFile(t, filepath.Join(dir, fn), 100, now.Add(-age))
+	}
+	writeFile(t, filepath.Join(dir, ".health"), 100, now.Add(-10*time.Hour))
+	for _, link := range []string{"info/app.log.3", "error/app.log.2"} {
+		if err := os.Symlink(filepath.Join(dir, link), filepath.Join(dir, filepath.Dir(link), "app.log")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		removed []string
+	)
+	q, err := New(dir,
+		WithMaxBytes(450),
+		WithInterval(time.Hour),
+		WithOnRemove(func(path string, size int64, reason string) {
+			if size != 100 || reason != ReasonMaxBytes {
+				t.Errorf("removed %s: %d, %s", path, size, reason)
+			}
+			mu.Lock()
+			removed = append(removed, strings.TrimPrefix(path, dir+"/"))
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// 700 bytes in total, the 3 oldest rotated files are removed
+	want := []string{"error/app.log.1", "info/app.log.1", "info/app.log.2"}
+	sort.Strings(removed)
+	if strings.Join(removed, ",") != strings.Join(want, ",") {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	for _, fn := range want {
+		if _, err := os.Stat(filepath.Join(dir, fn)); !os.IsNotExist(err) {
+			t.Fatalf("%s exists: %v", fn, err)
+		}
+	}
+	for _, fn := range []string{"info/app.log.3", "error/app.log.2", "error/app.log.3", ".health"} {
+		if _, err := os.Stat(filepath.Join(dir, fn)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nothing more to remove
+	if err := q.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("removed %v", removed)
+	}
+}
+
+func TestQuotaDirs(t *testing.T) {
+	dir, archive := t.TempDir(), t.TempDir()
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "info/app.log.1"), 100, now.Add(-2*time.Hour))
+	writeFile(t, filepath.Join(archive, "info/app.log.0.gz"), 100, now.Add(-3*time.Hour))
+	// neither counted nor removed
+	writeFile(t, filepath.Join(dir, "crash/1.crash"), 1000, now.Add(-5*time.Hour))
+	writeFile(t, filepath.Join(dir, "info/app.log.2.gz_tmp"), 1000, now.Add(-4*time.Hour))
+
+	var removed []string
+	q, err := New(dir,
+		WithMaxBytes(150),
+		WithInterval(time.Hour),
+		WithDirs(archive, filepath.Join(dir, "info")),
+		WithSkipDirs("crash"),
+		WithOnRemove(func(path string, size int64, reason string) {
+			removed = append(removed, path)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// the archived file is the oldest
+	if len(removed) != 1 || removed[0] != filepath.Join(archive, "info/app.log.0.gz") {
+		t.Fatalf("removed %v", removed)
+	}
+}
+
+func TestQuotaMinFree(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.log.1"), 100, time.Now().Add(-time.Hour))
+	writeFile(t, filepath.Join(dir, "app.log.2"), 100, time.Now())
+
+	var removed []string
+	// a threshold no file system meets removes every rotated file
+	q, err := New(dir, WithMinFree(1<<62), WithInterval(time.Hour),
+		WithOnRemove(func(path string, size int64, reason string) {
+			if reason != ReasonMinFree {
+				t.Errorf("reason %s", reason)
+			}
+			removed = append(removed, filepath.Base(path))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+	if strings.Join(removed, ",") != "app.log.1,app.log.2" {
+		t.Fatalf("removed %v", removed)
+	}
+
+	if _, err := New(dir); err == nil {
+		t.Fatal("a quota without limits is created")
+	}
+}
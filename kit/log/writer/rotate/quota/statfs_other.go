@@ -0,0 +1,11 @@
+//go:build !unix
+
+package quota
+
+import (
+	"errors"
+)
+
+func freeSpace(dir string) (int64, error) {
+	return 0, errors.New("free space is not supported on this platform")
+}
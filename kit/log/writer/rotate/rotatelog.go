@@ -42,6 +42,14 @@ type Config struct {
 	Pattern  string
 	Count    uint
 	Loc      *time.Location
+	// MaxSize rolls the file over to "name.1", "name.2" within a rotation, 0 disables
+	MaxSize int64
+	// Compressor compresses the rotated files, e.g. rotatelogs.Gzip
+	Compressor rotatelogs.Compressor
+	// ArchiveDir is where the rotated files are moved to
+	ArchiveDir string
+	// Handler is notified after each rotation
+	Handler rotatelogs.Handler
 }
 
 func NewWriter(opts ...Option) (io.Writer, error) {
@@ -80,13 +88,24 @@ func NewRotateLogger(rcfg *Config) (*rotatelogs.RotateLogs, error) {
 		}
 	}
 
-	// create Rotate Logs
-	return rotatelogs.New(
-		base+rcfg.Pattern,
+	opts := []rotatelogs.Option{
 		rotatelogs.WithLocation(rcfg.Loc),
 		rotatelogs.WithRotationCount(rcfg.Count),
 		rotatelogs.WithLinkName(base),              // 生成软链，指向最新日志文件
 		rotatelogs.WithMaxAge(rcfg.Age),            // 文件最大保存时间
 		rotatelogs.WithRotationTime(rcfg.Duration), // 日志切割时间间隔
-	)
+		rotatelogs.WithMaxSize(rcfg.MaxSize),       // 单个文件最大字节数
+	}
+	if rcfg.Compressor != nil {
+		opts = append(opts, rotatelogs.WithCompressor(rcfg.Compressor)) // 切割后压缩
+	}
+	if rcfg.ArchiveDir != "" {
+		opts = append(opts, rotatelogs.WithArchiveDir(rcfg.ArchiveDir)) // 切割后归档
+	}
+	if rcfg.Handler != nil {
+		opts = append(opts, rotatelogs.WithHandler(rcfg.Handler))
+	}
+
+	// create Rotate Logs
+	return rotatelogs.New(base+rcfg.Pattern, opts...)
 }
@@ -0,0 +1,37 @@
+package socket
+
+import (
+	"time"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithDialTimeout sets the timeout of dialing.
+func WithDialTimeout(timeout time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.DialTimeout = timeout
+	})
+}
+
+// WithWriteTimeout sets the timeout of a write.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.WriteTimeout = timeout
+	})
+}
+
+// WithBackoff sets the first and the longest wait before redialing.
+func WithBackoff(min, max time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MinBackoff = min
+		cfg.MaxBackoff = max
+	})
+}
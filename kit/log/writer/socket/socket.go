@@ -0,0 +1,82 @@
+// Package socket provides an io.Writer sending newline delimited entries,
+// e.g. JSON lines, over tcp, udp or a unix socket.
+package socket
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/diycoder/elf/kit/log/writer/internal/netconn"
+)
+
+type Config struct {
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewWriterConfig() *Config {
+	return &Config{
+		DialTimeout:  netconn.DefaultDialTimeout,
+		WriteTimeout: netconn.DefaultWriteTimeout,
+		MinBackoff:   netconn.DefaultMinBackoff,
+		MaxBackoff:   netconn.DefaultMaxBackoff,
+	}
+}
+
+// Writer writes every write as one line, a newline is appended when it is
+// missing. Over udp every line is a datagram. The connection is dialed on
+// the first write and redialed with a backoff after a failure, the writes
+// failing in the meantime are lost.
+type Writer struct {
+	conn *netconn.Conn
+}
+
+// NewWriter creates a writer to addr, the network is "tcp", "udp", "unix"
+// or "unixgram".
+func NewWriter(network, addr string, opts ...Option) (*Writer, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, err
+		}
+	case "unix", "unixgram":
+		if addr == "" {
+			return nil, fmt.Errorf("socket path cannot be empty")
+		}
+	default:
+		return nil, fmt.Errorf("invalid socket network %q", network)
+	}
+
+	cfg := NewWriterConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+	conn := netconn.New(network, addr, netconn.Config{
+		DialTimeout:  cfg.DialTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		MinBackoff:   cfg.MinBackoff,
+		MaxBackoff:   cfg.MaxBackoff,
+	})
+	return &Writer{conn: conn}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	b := p
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		b = make([]byte, len(p)+1)
+		copy(b, p)
+		b[len(p)] = '\n'
+	}
+	if _, err := w.conn.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection.
+func (w *Writer) Close() error {
+	return w.conn.Close()
+}
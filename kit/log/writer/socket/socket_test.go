@@ -0,0 +1,92 @@
+package socket
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/diycoder/elf/kit/log/writer/internal/netconn"
+)
+
+func TestWriterTCP(t *testing.T) {
+	// an address nobody listens on yet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w, err := NewWriter("tcp", addr, WithBackoff(20*time.Millisecond, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("lost")); err == nil {
+		t.Fatal("write should fail without a listener")
+	}
+	if _, err := w.Write([]byte("lost")); err != netconn.ErrUnavailable {
+		t.Fatalf("want ErrUnavailable while backing off, got %v", err)
+	}
+
+	// the collector comes up, the writer reconnects after the backoff
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := w.Write([]byte(`{"msg":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("{\"msg\":\"b\"}\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"msg":"a"}`, `{"msg":"b"}`} {
+		if got := <-lines; got != want {
+			t.Fatalf("want %s, got %s", want, got)
+		}
+	}
+}
+
+func TestWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := NewWriter("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte(`{"msg":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "{\"msg\":\"a\"}\n" {
+		t.Fatalf("unexpected datagram %q", got)
+	}
+}
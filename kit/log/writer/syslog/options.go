@@ -0,0 +1,80 @@
+package syslog
+
+import (
+	"time"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (f optionFunc) apply(cfg *Config) {
+	f(cfg)
+}
+
+// WithFacility sets the facility of the messages, default User.
+func WithFacility(facility Facility) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Facility = facility
+	})
+}
+
+// WithSeverity sets the severity of the messages, default Info.
+func WithSeverity(severity Severity) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Severity = severity
+	})
+}
+
+// WithSeverityFunc chooses the severity of every message, e.g. from the
+// level written by the encoder.
+func WithSeverityFunc(fn func(msg []byte) Severity) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.SeverityFunc = fn
+	})
+}
+
+// WithHostname sets the HOSTNAME header, default the host name.
+func WithHostname(hostname string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Hostname = hostname
+	})
+}
+
+// WithAppName sets the APP-NAME header, default the program name.
+func WithAppName(name string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.AppName = name
+	})
+}
+
+// WithMsgID sets the MSGID header.
+func WithMsgID(id string) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MsgID = id
+	})
+}
+
+// WithDialTimeout sets the timeout of dialing.
+func WithDialTimeout(timeout time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.DialTimeout = timeout
+	})
+}
+
+// WithWriteTimeout sets the timeout of a write.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.WriteTimeout = timeout
+	})
+}
+
+// WithBackoff sets the first and the longest wait before redialing.
+func WithBackoff(min, max time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.MinBackoff = min
+		cfg.MaxBackoff = max
+	})
+}
@@ -0,0 +1,219 @@
+// Package syslog provides an io.Writer sending RFC 5424 syslog messages over
+// udp, tcp or a unix socket.
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diycoder/elf/kit/log/writer/internal/netconn"
+)
+
+// Facility is the syslog facility of the messages.
+type Facility int
+
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	AuthPriv
+	Ftp
+)
+
+const (
+	Local0 Facility = iota + 16
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+var facilities = map[string]Facility{
+	"kern": Kern, "user": User, "mail": Mail, "daemon": Daemon,
+	"auth": Auth, "syslog": Syslog, "lpr": Lpr, "news": News,
+	"uucp": Uucp, "cron": Cron, "authpriv": AuthPriv, "ftp": Ftp,
+	"local0": Local0, "local1": Local1, "local2": Local2, "local3": Local3,
+	"local4": Local4, "local5": Local5, "local6": Local6, "local7": Local7,
+}
+
+// ParseFacility parses a facility name such as "user" or "local0".
+func ParseFacility(text string) (Facility, error) {
+	if f, ok := facilities[strings.ToLower(text)]; ok {
+		return f, nil
+	}
+	return User, fmt.Errorf("invalid syslog facility %q", text)
+}
+
+// Severity is the syslog severity of a message.
+type Severity int
+
+const (
+	Emerg Severity = iota
+	Alert
+	Crit
+	Err
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+var severities = map[string]Severity{
+	"emerg": Emerg, "alert": Alert, "crit": Crit, "err": Err, "error": Err,
+	"warning": Warning, "warn": Warning, "notice": Notice, "info": Info, "debug": Debug,
+}
+
+// ParseSeverity parses a severity name such as "err" or "info", the log
+// level names error and warn are accepted too.
+func ParseSeverity(text string) (Severity, error) {
+	if s, ok := severities[strings.ToLower(text)]; ok {
+		return s, nil
+	}
+	return Info, fmt.Errorf("invalid syslog severity %q", text)
+}
+
+const nilValue = "-"
+
+type Config struct {
+	Facility Facility
+	Severity Severity
+	// SeverityFunc, if set, chooses the severity of every message
+	SeverityFunc func(msg []byte) Severity
+	Hostname     string
+	AppName      string
+	MsgID        string
+
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewWriterConfig() *Config {
+	hostname, _ := os.Hostname()
+	return &Config{
+		Facility:     User,
+		Severity:     Info,
+		Hostname:     hostname,
+		AppName:      filepath.Base(os.Args[0]),
+		DialTimeout:  netconn.DefaultDialTimeout,
+		WriteTimeout: netconn.DefaultWriteTimeout,
+		MinBackoff:   netconn.DefaultMinBackoff,
+		MaxBackoff:   netconn.DefaultMaxBackoff,
+	}
+}
+
+// Writer sends every write as one syslog message. Over tcp and unix the
+// messages are framed by octet counting (RFC 6587). The connection is
+// dialed on the first write and redialed with a backoff after a failure.
+type Writer struct {
+	cfg    *Config
+	conn   *netconn.Conn
+	stream bool
+	header string
+}
+
+// NewWriter creates a writer to addr, the network is "udp", "tcp", "unix"
+// or "unixgram", e.g. "unixgram" and "/dev/log".
+func NewWriter(network, addr string, opts ...Option) (*Writer, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, err
+		}
+	case "unix", "unixgram":
+		if addr == "" {
+			return nil, fmt.Errorf("syslog socket path cannot be empty")
+		}
+	default:
+		return nil, fmt.Errorf("invalid syslog network %q", network)
+	}
+
+	cfg := NewWriterConfig()
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	w := &Writer{
+		cfg:    cfg,
+		stream: netconn.Stream(network),
+		conn: netconn.New(network, addr, netconn.Config{
+			DialTimeout:  cfg.DialTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			MinBackoff:   cfg.MinBackoff,
+			MaxBackoff:   cfg.MaxBackoff,
+		}),
+	}
+	// HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA follow the timestamp
+	w.header = strings.Join([]string{
+		header(cfg.Hostname, 255),
+		header(cfg.AppName, 48),
+		strconv.Itoa(os.Getpid()),
+		header(cfg.MsgID, 32),
+		nilValue,
+	}, " ")
+	return w, nil
+}
+
+// header returns a header value of printable ascii cut to max.
+func header(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nilValue
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\r\n")
+
+	severity := w.cfg.Severity
+	if w.cfg.SeverityFunc != nil {
+		severity = w.cfg.SeverityFunc(msg)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s ",
+		int(w.cfg.Facility)*8+int(severity),
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.header)
+	b.Write(msg)
+
+	out := b.Bytes()
+	if w.stream {
+		out = append([]byte(strconv.Itoa(len(out))+" "), out...)
+	}
+	if _, err := w.conn.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection.
+func (w *Writer) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,101 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var messageRegexp = regexp.MustCompile(`^<(\d+)>1 \S+ host app \d+ - - (.*)$`)
+
+func TestWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := NewWriter("udp", pc.LocalAddr().String(),
+		WithFacility(Local0), WithHostname("host"), WithAppName("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("hello world\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := messageRegexp.FindStringSubmatch(string(buf[:n]))
+	if m == nil {
+		t.Fatalf("unexpected message %q", buf[:n])
+	}
+	// local0 * 8 + info
+	if m[1] != "134" || m[2] != "hello world" {
+		t.Fatalf("unexpected priority %s or message %q", m[1], m[2])
+	}
+}
+
+func TestWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := NewWriter("tcp", ln.Addr().String(),
+		WithHostname("host"), WithAppName("app"),
+		WithSeverityFunc(func(msg []byte) Severity {
+			if string(msg) == "boom" {
+				return Err
+			}
+			return Info
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	frames := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(size[:len(size)-1])
+			b := make([]byte, n)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return
+			}
+			frames <- string(b)
+		}
+	}()
+
+	for _, msg := range []string{"fine", "boom"} {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"14", "11"} {
+		m := messageRegexp.FindStringSubmatch(<-frames)
+		if m == nil || m[1] != want {
+			t.Fatalf("want priority %s, got %q", want, m)
+		}
+	}
+}
@@ -1,22 +1,64 @@
 package log
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"runtime"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// exit is replaced in tests
+var exit = os.Exit
+
 type zLogger struct {
 	L            *zap.Logger
 	errL         *zap.Logger
 	levelEnabler Level
+	// fatalHook runs instead of exiting after a fatal entry
+	fatalHook func()
 }
 
 func newZapLogger(lopts ...Option) (Logger, error) {
+	opts, zOpts, err := zapOptionsOf(lopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	zl := &zLogger{levelEnabler: opts.LevelEnabler}
+	return zl.Init(zOpts...)
+}
+
+// NewZapLogger creates a logger from zap options, e.g. on a core built by
+// NewCore with WithZapCore.
+func NewZapLogger(zOpts ...ZapOption) (Logger, error) {
+	zl := &zLogger{levelEnabler: DebugLevel}
+	return zl.Init(zOpts...)
+}
+
+// NewCore creates the zap core a logger with the options writes to, the
+// fields of the options are added to the core. The caller and stacktrace
+// options belong to the logger and are ignored.
+func NewCore(lopts ...Option) (zapcore.Core, error) {
+	_, zOpts, err := zapOptionsOf(lopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := newZapOption()
+	for _, opt := range zOpts {
+		opt.apply(opts)
+	}
+	return opts.withHooks(opts.newCore(opts.writer)).With(opts.fields), nil
+}
+
+func zapOptionsOf(lopts ...Option) (Options, []ZapOption, error) {
 	opts := Options{
 		AddStack:     ErrorLevel,
 		LevelEnabler: DebugLevel,
+		MaxLevel:     FatalLevel,
 	}
 
 	for _, opt := range lopts {
@@ -32,21 +74,23 @@ func newZapLogger(lopts ...Option) (Logger, error) {
 		withZapErrWriter,
 		withZapFields,
 		withZapLevelEnabler,
+		withZapSampling,
+		withZapRedactor,
+		withZapHooks,
+		withZapFatalHook,
 	}
 
 	for _, fn := range optFunc {
 		opt, err := fn(opts)
 		if err != nil {
-			return nil, err
+			return opts, nil, err
 		}
 		if opt == nil {
 			continue
 		}
 		zOpts = append(zOpts, opt)
 	}
-
-	zl := &zLogger{levelEnabler: opts.LevelEnabler}
-	return zl.Init(zOpts...)
+	return opts, zOpts, nil
 }
 
 func (zl *zLogger) Init(zOpts ...ZapOption) (Logger, error) {
@@ -55,11 +99,15 @@ func (zl *zLogger) Init(zOpts ...ZapOption) (Logger, error) {
 		opt.apply(opts)
 	}
 
-	core := zapcore.NewCore(opts.encoder, opts.writer, opts.levelEnabler)
-	zl.L = zap.New(core, opts.zOpts...).With(opts.fields...)
+	core := opts.core
+	if core == nil {
+		core = opts.newCore(opts.writer)
+	}
+	zl.L = zap.New(opts.withHooks(core), opts.zOpts...).With(opts.fields...)
+	zl.fatalHook = opts.fatalHook
 
 	if opts.errWriter != nil {
-		errCore := zapcore.NewCore(opts.encoder, opts.errWriter, opts.levelEnabler)
+		errCore := opts.withHooks(opts.newCore(opts.errWriter))
 		zl.errL = zap.New(errCore, opts.zOpts...).With(opts.fields...)
 	}
 
@@ -77,7 +125,10 @@ func (zl *zLogger) Debugf(format string, args ...interface{}) {
 	if !zl.levelEnabler.Enabled(DebugLevel) {
 		return
 	}
-	zl.L.Debug(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.DebugLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
 func (zl *zLogger) Info(args ...interface{}) {
@@ -91,7 +142,10 @@ func (zl *zLogger) Infof(format string, args ...interface{}) {
 	if !zl.levelEnabler.Enabled(InfoLevel) {
 		return
 	}
-	zl.L.Info(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.InfoLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
 func (zl *zLogger) Warn(args ...interface{}) {
@@ -105,7 +159,10 @@ func (zl *zLogger) Warnf(format string, args ...interface{}) {
 	if !zl.levelEnabler.Enabled(WarnLevel) {
 		return
 	}
-	zl.L.Warn(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.WarnLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
 func (zl *zLogger) Error(args ...interface{}) {
@@ -124,10 +181,16 @@ func (zl *zLogger) Errorf(format string, args ...interface{}) {
 		return
 	}
 	if zl.errL != nil {
-		zl.errL.Error(fmt.Sprintf(format, args...))
+		if ce := zl.errL.Check(zapcore.ErrorLevel, format); ce != nil {
+			ce.Message = fmt.Sprintf(format, args...)
+			ce.Write()
+		}
 		return
 	}
-	zl.L.Error(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.ErrorLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
 func (zl *zLogger) Trace(args ...interface{}) {
@@ -141,7 +204,10 @@ func (zl *zLogger) Tracef(format string, args ...interface{}) {
 	if !zl.levelEnabler.Enabled(DebugLevel) {
 		return
 	}
-	zl.L.Debug(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.DebugLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
 func (zl *zLogger) Panic(args ...interface{}) {
@@ -160,32 +226,164 @@ func (zl *zLogger) Panicf(format string, args ...interface{}) {
 		return
 	}
 	if zl.errL != nil {
-		zl.errL.Error(fmt.Sprintf(format, args...))
+		if ce := zl.errL.Check(zapcore.ErrorLevel, format); ce != nil {
+			ce.Message = fmt.Sprintf(format, args...)
+			ce.Write()
+		}
 		return
 	}
-	zl.L.Error(fmt.Sprintf(format, args...))
+	if ce := zl.L.Check(zapcore.ErrorLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write()
+	}
 }
 
+// Fatal writes the entry at error level, then flushes the logger and exits
+// with status 1 unless a fatal hook is set.
 func (zl *zLogger) Fatal(args ...interface{}) {
-	if !zl.levelEnabler.Enabled(FatalLevel) {
+	if zl.levelEnabler.Enabled(FatalLevel) {
+		zl.errLogger().Error(fmt.Sprint(args...))
+	}
+	zl.fatal()
+}
+
+func (zl *zLogger) Fatalf(format string, args ...interface{}) {
+	if zl.levelEnabler.Enabled(FatalLevel) {
+		if ce := zl.errLogger().Check(zapcore.ErrorLevel, format); ce != nil {
+			ce.Message = fmt.Sprintf(format, args...)
+			ce.Write()
+		}
+	}
+	zl.fatal()
+}
+
+func (zl *zLogger) fatal() {
+	if zl.fatalHook != nil {
+		zl.fatalHook()
 		return
 	}
-	if zl.errL != nil {
-		zl.errL.Error(fmt.Sprint(args...))
+	_ = zl.Sync()
+	exit(1)
+}
+
+func (zl *zLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if !zl.levelEnabler.Enabled(DebugLevel) {
 		return
 	}
-	zl.L.Error(fmt.Sprint(args...))
+	zl.L.Debug(msg, KeysAndValues(keysAndValues...)...)
 }
 
-func (zl *zLogger) Fatalf(format string, args ...interface{}) {
-	if !zl.levelEnabler.Enabled(FatalLevel) {
+func (zl *zLogger) Infow(msg string, keysAndValues ...interface{}) {
+	if !zl.levelEnabler.Enabled(InfoLevel) {
 		return
 	}
-	if zl.errL != nil {
-		zl.errL.Error(fmt.Sprintf(format, args...))
+	zl.L.Info(msg, KeysAndValues(keysAndValues...)...)
+}
+
+func (zl *zLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	if !zl.levelEnabler.Enabled(WarnLevel) {
 		return
 	}
-	zl.L.Error(fmt.Sprintf(format, args...))
+	zl.L.Warn(msg, KeysAndValues(keysAndValues...)...)
+}
+
+func (zl *zLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	if !zl.levelEnabler.Enabled(ErrorLevel) {
+		return
+	}
+	zl.errLogger().Error(msg, KeysAndValues(keysAndValues...)...)
+}
+
+func (zl *zLogger) DebugCtx(ctx context.Context, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(DebugLevel) {
+		return
+	}
+	zl.L.Debug(fmt.Sprint(args...), contextZapFields(ctx)...)
+}
+
+func (zl *zLogger) DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(DebugLevel) {
+		return
+	}
+	if ce := zl.L.Check(zapcore.DebugLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (zl *zLogger) InfoCtx(ctx context.Context, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(InfoLevel) {
+		return
+	}
+	zl.L.Info(fmt.Sprint(args...), contextZapFields(ctx)...)
+}
+
+func (zl *zLogger) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(InfoLevel) {
+		return
+	}
+	if ce := zl.L.Check(zapcore.InfoLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (zl *zLogger) WarnCtx(ctx context.Context, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(WarnLevel) {
+		return
+	}
+	zl.L.Warn(fmt.Sprint(args...), contextZapFields(ctx)...)
+}
+
+func (zl *zLogger) WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(WarnLevel) {
+		return
+	}
+	if ce := zl.L.Check(zapcore.WarnLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (zl *zLogger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(ErrorLevel) {
+		return
+	}
+	zl.errLogger().Error(fmt.Sprint(args...), contextZapFields(ctx)...)
+}
+
+func (zl *zLogger) ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	if !zl.levelEnabler.Enabled(ErrorLevel) {
+		return
+	}
+	if ce := zl.errLogger().Check(zapcore.ErrorLevel, format); ce != nil {
+		ce.Message = fmt.Sprintf(format, args...)
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+// LogCaller logs msg with the caller at pc, levels above error are written
+// at error like Panic and Fatal.
+func (zl *zLogger) LogCaller(pc uintptr, lv Level, msg string, fields ...Field) {
+	if !zl.levelEnabler.Enabled(lv) {
+		return
+	}
+
+	l, zlv := zl.L, zapcore.ErrorLevel
+	if lv < ErrorLevel {
+		zlv, _ = zapLevelParse(lv)
+	} else {
+		l = zl.errLogger()
+	}
+	ce := l.Check(zlv, msg)
+	if ce == nil {
+		return
+	}
+	if ce.Caller.Defined && pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+	ce.Write(fields...)
 }
 
 func (zl *zLogger) WithField(key string, value interface{}) Logger {
@@ -208,6 +406,21 @@ func (zl *zLogger) WithFields(fields map[string]interface{}) Logger {
 	return zl.clone(zfields...)
 }
 
+func (zl *zLogger) With(fields ...Field) Logger {
+	if len(fields) == 0 {
+		return zl
+	}
+	return zl.clone(fields...)
+}
+
+func (zl *zLogger) WithContext(ctx context.Context) Logger {
+	fields := contextZapFields(ctx)
+	if len(fields) == 0 {
+		return zl
+	}
+	return zl.clone(fields...)
+}
+
 func (zl *zLogger) SetLogLevel(lv Level) error {
 	if _, err := zapLevelParse(lv); err != nil {
 		return err
@@ -216,16 +429,47 @@ func (zl *zLogger) SetLogLevel(lv Level) error {
 	return nil
 }
 
+func (zl *zLogger) Sync() error {
+	err := zl.L.Sync()
+	if zl.errL != nil {
+		if eerr := zl.errL.Sync(); err == nil {
+			err = eerr
+		}
+	}
+	return err
+}
+
 func (zl *zLogger) clone(fields ...zapcore.Field) *zLogger {
 	if zl.errL != nil {
 		return &zLogger{
 			L:            zl.L.With(fields...),
 			errL:         zl.errL.With(fields...),
 			levelEnabler: zl.levelEnabler,
+			fatalHook:    zl.fatalHook,
 		}
 	}
 
-	return &zLogger{L: zl.L.With(fields...), levelEnabler: zl.levelEnabler}
+	return &zLogger{L: zl.L.With(fields...), levelEnabler: zl.levelEnabler, fatalHook: zl.fatalHook}
+}
+
+// errLogger returns the logger of error and above levels.
+func (zl *zLogger) errLogger() *zap.Logger {
+	if zl.errL != nil {
+		return zl.errL
+	}
+	return zl.L
+}
+
+func contextZapFields(ctx context.Context) []zapcore.Field {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return nil
+	}
+	zfields := make([]zapcore.Field, 0, len(fields))
+	for k, v := range fields {
+		zfields = append(zfields, zap.Any(k, v))
+	}
+	return zfields
 }
 
 func zapLevelParse(lv Level) (zapcore.Level, error) {
@@ -17,6 +17,31 @@ type zapOptions struct {
 	levelEnabler zapcore.LevelEnabler
 	writer       zapcore.WriteSyncer
 	errWriter    zapcore.WriteSyncer
+	sampling     *SamplingConfig
+	redactor     *Redactor
+	hooks        []Hook
+	core         zapcore.Core
+	fatalHook    func()
+}
+
+func (opts *zapOptions) newCore(w zapcore.WriteSyncer) zapcore.Core {
+	encoder := opts.encoder
+	if opts.redactor != nil {
+		encoder = newRedactEncoder(encoder, opts.redactor)
+	}
+	core := zapcore.NewCore(encoder, w, opts.levelEnabler)
+	if opts.sampling != nil {
+		core = newSamplingCore(core, opts.sampling)
+	}
+	return core
+}
+
+// withHooks tees the hook core next to core.
+func (opts *zapOptions) withHooks(core zapcore.Core) zapcore.Core {
+	if len(opts.hooks) == 0 {
+		return core
+	}
+	return zapcore.NewTee(core, NewHookCore(opts.redactor, opts.hooks...))
 }
 
 func newZapOption() *zapOptions {
@@ -62,6 +87,56 @@ func WithZapLevelEnabler(lv Level) ZapOption {
 	})
 }
 
+// WithZapLevelRange enables the levels between min and max.
+func WithZapLevelRange(min, max Level) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		zmin, _ := zapLevelParse(min)
+		zmax, err := zapLevelParse(max)
+		if err != nil {
+			zmax = zapcore.FatalLevel
+		}
+		opts.levelEnabler = zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= zmin && l <= zmax
+		})
+	})
+}
+
+// WithZapSampling wraps the cores in zap's sampler.
+func WithZapSampling(cfg SamplingConfig) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		opts.sampling = &cfg
+	})
+}
+
+// WithZapRedactor wraps the encoder to mask sensitive data.
+func WithZapRedactor(r *Redactor) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		opts.redactor = r
+	})
+}
+
+// WithZapHooks hands the entries to hooks, see Hook.
+func WithZapHooks(hooks ...Hook) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		opts.hooks = append(opts.hooks, hooks...)
+	})
+}
+
+// WithZapFatalHook runs fn instead of exiting after a fatal entry is written.
+func WithZapFatalHook(fn func()) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		opts.fatalHook = fn
+	})
+}
+
+// WithZapCore makes the logger write to core, the encoder, writer, level and
+// sampling options are then ignored.
+func WithZapCore(core zapcore.Core) ZapOption {
+	return zapOptionFunc(func(opts *zapOptions) {
+		opts.core = core
+	})
+}
+
 func WithZapWriter(w io.Writer) ZapOption {
 	return zapOptionFunc(func(opts *zapOptions) {
 		opts.writer = zapcore.AddSync(w)
@@ -124,9 +199,21 @@ func withZapEncoder(opts Options) (ZapOption, error) {
 		cfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
 	case ChinaMilliTimeEncoder:
 		cfg.EncodeTime = CSTLayoutEncoder
+	case LayoutTimeEncoder:
+		layout := opts.EncoderCfg.TimeLayout
+		if layout == "" {
+			layout = DefaultTimeLayout
+		}
+		cfg.EncodeTime = zapcore.TimeEncoderOfLayout(layout)
 	default:
 		return nil, fmt.Errorf("Invaild EncodeTime: %v ", opts.EncoderCfg.EncodeTime)
 	}
+	if loc := opts.EncoderCfg.TimeLocation; loc != nil {
+		encodeTime := cfg.EncodeTime
+		cfg.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			encodeTime(t.In(loc), enc)
+		}
+	}
 
 	cfg.CallerKey = opts.EncoderCfg.CallerKey
 
@@ -168,13 +255,44 @@ func rfc3339MilliTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 
 // China Standard Time Layout
 func CSTLayoutEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
+	enc.AppendString(t.Format(DefaultTimeLayout))
 }
 
 func javaCustomCaller(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString("[" + os.Args[0] + "]" + "[" + caller.TrimmedPath() + "]")
 }
 
+func withZapSampling(opts Options) (ZapOption, error) {
+	if opts.Sampling == nil {
+		return nil, nil
+	}
+	if opts.Sampling.Initial < 0 || opts.Sampling.Thereafter < 0 {
+		return nil, fmt.Errorf("Invalid sampling: %+v ", *opts.Sampling)
+	}
+	return WithZapSampling(*opts.Sampling), nil
+}
+
+func withZapRedactor(opts Options) (ZapOption, error) {
+	if opts.Redactor != nil {
+		return WithZapRedactor(opts.Redactor), nil
+	}
+	return nil, nil
+}
+
+func withZapHooks(opts Options) (ZapOption, error) {
+	if len(opts.Hooks) > 0 {
+		return WithZapHooks(opts.Hooks...), nil
+	}
+	return nil, nil
+}
+
+func withZapFatalHook(opts Options) (ZapOption, error) {
+	if opts.FatalHook != nil {
+		return WithZapFatalHook(opts.FatalHook), nil
+	}
+	return nil, nil
+}
+
 func withZapWriter(opts Options) (ZapOption, error) {
 	if opts.Writer != nil {
 		return WithZapWriter(opts.Writer), nil
@@ -201,5 +319,11 @@ func withZapFields(opts Options) (ZapOption, error) {
 }
 
 func withZapLevelEnabler(opts Options) (ZapOption, error) {
+	if opts.MaxLevel < FatalLevel {
+		if opts.MaxLevel < opts.LevelEnabler {
+			return nil, fmt.Errorf("Invalid level range: %v - %v ", opts.LevelEnabler, opts.MaxLevel)
+		}
+		return WithZapLevelRange(opts.LevelEnabler, opts.MaxLevel), nil
+	}
 	return WithZapLevelEnabler(opts.LevelEnabler), nil
 }
@@ -0,0 +1,254 @@
+package elf
+
+import (
+	"os"
+	"strings"
+
+	"github.com/diycoder/elf/config"
+	"github.com/diycoder/elf/config/source/file"
+	"github.com/diycoder/elf/plugin"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Options decide which plugins are initialized by Run.
+type Options struct {
+	// Plugins are plugin instances initialized unless disabled, when Enabled
+	// is set only the instances it names are initialized
+	Plugins []plugin.Plugin
+	// Enabled are factory names of plugins to initialize, in order
+	Enabled []string
+	// Disabled are factory or plugin names which are never initialized
+	Disabled []string
+	// Optional are factory or plugin names whose init failure does not
+	// abort the startup
+	Optional []string
+	// PluginsConfig is a json/yaml/toml file providing plugins.enabled,
+	// plugins.disabled and plugins.optional
+	PluginsConfig string
+}
+
+type Option func(o *Options)
+
+// WithPlugins adds plugin instances.
+func WithPlugins(p ...plugin.Plugin) Option {
+	return func(o *Options) {
+		o.Plugins = append(o.Plugins, p...)
+	}
+}
+
+// WithEnabled sets the factory names of the plugins to initialize, in order.
+func WithEnabled(names ...string) Option {
+	return func(o *Options) {
+		o.Enabled = names
+	}
+}
+
+// WithDisabled sets the plugins never initialized.
+func WithDisabled(names ...string) Option {
+	return func(o *Options) {
+		o.Disabled = names
+	}
+}
+
+// WithOptional sets the plugins whose init failure does not abort the startup.
+func WithOptional(names ...string) Option {
+	return func(o *Options) {
+		o.Optional = names
+	}
+}
+
+// WithPluginsConfig sets the config file enabling and disabling plugins.
+func WithPluginsConfig(path string) Option {
+	return func(o *Options) {
+		o.PluginsConfig = path
+	}
+}
+
+const (
+	pluginsFlag         = "plugins"
+	disabledPluginsFlag = "disabled_plugins"
+	optionalPluginsFlag = "optional_plugins"
+	pluginsConfigFlag   = "plugins_config"
+)
+
+func elfFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    pluginsFlag,
+			Usage:   "Set the plugins to enable in order, e.g. \"log,version,apollo,store\".",
+			EnvVars: []string{"ELF_PLUGINS"},
+		},
+		&cli.StringFlag{
+			Name:    disabledPluginsFlag,
+			Usage:   "Set the plugins to disable, e.g. \"nacos\".",
+			EnvVars: []string{"ELF_DISABLED_PLUGINS"},
+		},
+		&cli.StringFlag{
+			Name:    optionalPluginsFlag,
+			Usage:   "Set the plugins whose init failure does not abort the startup, e.g. \"nacos\".",
+			EnvVars: []string{"ELF_OPTIONAL_PLUGINS"},
+		},
+		&cli.StringFlag{
+			Name:    pluginsConfigFlag,
+			Usage:   "Set the config file providing plugins.enabled, plugins.disabled and plugins.optional.",
+			EnvVars: []string{"ELF_PLUGINS_CONFIG"},
+		},
+	}
+}
+
+// Run resolves the plugins from the options, the plugins config file and the
+// plugins flags (in increasing priority) and initializes them.
+func Run(opts ...Option) error {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	plugins, optional, err := resolvePlugins(options, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	// the flags are only known to Run, Init would accept and ignore them
+	return initialize(plugins, optional, elfFlags()...)
+}
+
+// resolvePlugins returns the plugins to initialize and the names of the
+// optional ones.
+func resolvePlugins(options Options, args []string) ([]plugin.Plugin, map[string]bool, error) {
+	if path, ok := lookupFlag(args, pluginsConfigFlag, "ELF_PLUGINS_CONFIG"); ok {
+		options.PluginsConfig = path
+	}
+	if options.PluginsConfig != "" {
+		if err := loadPluginsConfig(&options); err != nil {
+			return nil, nil, err
+		}
+	}
+	if v, ok := lookupFlag(args, pluginsFlag, "ELF_PLUGINS"); ok {
+		options.Enabled = splitNames(v)
+	}
+	if v, ok := lookupFlag(args, disabledPluginsFlag, "ELF_DISABLED_PLUGINS"); ok {
+		options.Disabled = splitNames(v)
+	}
+	if v, ok := lookupFlag(args, optionalPluginsFlag, "ELF_OPTIONAL_PLUGINS"); ok {
+		options.Optional = splitNames(v)
+	}
+
+	disabled := pluginNameSet(options.Disabled)
+	optional := pluginNameSet(options.Optional)
+
+	if len(options.Enabled) > 0 {
+		plugins, err := enabledPlugins(options.Enabled, options.Plugins, disabled)
+		return plugins, optional, err
+	}
+
+	explicit := options.Plugins
+	if len(explicit) == 0 {
+		explicit = DefaultPlugins()
+	}
+	plugins := make([]plugin.Plugin, 0, len(explicit))
+	seen := make(map[string]bool)
+	for _, p := range explicit {
+		if disabled[p.String()] || seen[p.String()] {
+			continue
+		}
+		seen[p.String()] = true
+		plugins = append(plugins, p)
+	}
+	return plugins, optional, nil
+}
+
+// enabledPlugins returns the plugins named in enabled, in order. A plugin
+// instance passed by WithPlugins is used for its name instead of a new one
+// from the factory, the instances not named are not initialized.
+func enabledPlugins(enabled []string, instances []plugin.Plugin, disabled map[string]bool) ([]plugin.Plugin, error) {
+	byName := make(map[string]plugin.Plugin, len(instances))
+	for _, p := range instances {
+		byName[p.String()] = p
+	}
+
+	plugins := make([]plugin.Plugin, 0, len(enabled))
+	seen := make(map[string]bool)
+	for _, name := range enabled {
+		pname := plugin.Name(name)
+		if disabled[name] || disabled[pname] || seen[pname] {
+			continue
+		}
+		seen[pname] = true
+		if p, ok := byName[pname]; ok {
+			plugins = append(plugins, p)
+			continue
+		}
+		p, err := plugin.New(name)
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// pluginNameSet returns a set of the names, a factory name also matches the
+// name of the plugin instance it creates.
+func pluginNameSet(names []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+		set[plugin.Name(name)] = true
+	}
+	return set
+}
+
+func loadPluginsConfig(options *Options) error {
+	c, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Load(file.NewSource(file.WithPath(options.PluginsConfig))); err != nil {
+		return err
+	}
+
+	if enabled := c.Get("plugins", "enabled").StringSlice(nil); len(enabled) > 0 {
+		options.Enabled = enabled
+	}
+	if disabled := c.Get("plugins", "disabled").StringSlice(nil); len(disabled) > 0 {
+		options.Disabled = disabled
+	}
+	if optional := c.Get("plugins", "optional").StringSlice(nil); len(optional) > 0 {
+		options.Optional = optional
+	}
+	return nil
+}
+
+// lookupFlag finds a flag value before the cli app parses the arguments,
+// since the flags of the plugins must be known beforehand.
+func lookupFlag(args []string, name, env string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		trimmed := strings.TrimLeft(arg, "-")
+		if trimmed == arg {
+			continue
+		}
+		if trimmed == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if strings.HasPrefix(trimmed, name+"=") {
+			return strings.TrimPrefix(trimmed, name+"="), true
+		}
+	}
+	return os.LookupEnv(env)
+}
+
+func splitNames(v string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+package elf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diycoder/elf/plugin"
+)
+
+func pluginNames(ps []plugin.Plugin) []string {
+	names := make([]string, 0, len(ps))
+	for _, p := range ps {
+		names = append(names, p.String())
+	}
+	return names
+}
+
+func TestResolvePlugins(t *testing.T) {
+	ps, _, err := resolvePlugins(Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginNames(ps); len(got) != 2 || got[0] != "log_setting" || got[1] != "version" {
+		t.Fatalf("unexpected default plugins %v", got)
+	}
+
+	ps, _, err = resolvePlugins(Options{Enabled: []string{"store", "log"}}, []string{"--disabled_plugins=store", "--plugins", "version,log,store"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginNames(ps); len(got) != 2 || got[0] != "version" || got[1] != "log_setting" {
+		t.Fatalf("unexpected flag plugins %v", got)
+	}
+
+	_, optional, err := resolvePlugins(Options{Optional: []string{"nacos"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !optional["nacos"] || !optional["nacos_config"] {
+		t.Fatalf("unexpected optional plugins %v", optional)
+	}
+
+	custom := plugin.NewPlugin(plugin.WithName("version"))
+	extra := plugin.NewPlugin(plugin.WithName("extra"))
+	ps, _, err = resolvePlugins(Options{Plugins: []plugin.Plugin{extra, custom}}, []string{"--plugins=log,version"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginNames(ps); len(got) != 2 || got[0] != "log_setting" || ps[1] != custom {
+		t.Fatalf("unexpected enabled plugins %v", got)
+	}
+
+	if _, _, err = resolvePlugins(Options{Enabled: []string{"unknown"}}, nil); err == nil {
+		t.Fatal("expected unknown plugin error")
+	}
+}
+
+func TestResolvePluginsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	data := []byte("plugins:\n  enabled: [log, version, apollo]\n  disabled: [apollo]\n")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ps, _, err := resolvePlugins(Options{PluginsConfig: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginNames(ps); len(got) != 2 || got[0] != "log_setting" || got[1] != "version" {
+		t.Fatalf("unexpected config plugins %v", got)
+	}
+}
@@ -0,0 +1,14 @@
+package plugin
+
+import "context"
+
+// Action is a runtime operation of a plugin, e.g. changing the log level.
+// The args come from the query or form of the admin request.
+type Action func(ctx context.Context, args map[string]string) (interface{}, error)
+
+// Actioner is an optional interface a plugin can implement to be
+// reconfigured at runtime through the admin plugin.
+type Actioner interface {
+	// Actions returns the actions of the plugin keyed by name.
+	Actions() map[string]Action
+}
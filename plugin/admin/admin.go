@@ -0,0 +1,125 @@
+// Package admin serves a token protected http surface on a separate port to
+// inspect the registered plugins and run their runtime actions.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/diycoder/elf/plugin"
+	"github.com/diycoder/elf/plugin/log"
+)
+
+const TokenHeader = "X-Admin-Token"
+
+type pluginInfo struct {
+	Name    string   `json:"name"`
+	State   string   `json:"state"`
+	Policy  string   `json:"policy"`
+	Health  bool     `json:"health"`
+	Actions []string `json:"actions"`
+}
+
+type response struct {
+	Plugin string      `json:"plugin,omitempty"`
+	Action string      `json:"action,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Handler returns the admin endpoints protected by token:
+//
+//	GET  /admin/plugins                  list the plugins, their state and actions
+//	POST /admin/plugins/{name}/{action}  run an action, args from query or form
+func Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/plugins", listPlugins)
+	mux.HandleFunc("POST /admin/plugins/{name}/{action}", runAction)
+	return authorize(token, mux)
+}
+
+// authorize accepts the token from the X-Admin-Token header or as bearer token.
+func authorize(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(TokenHeader)
+		if got == "" {
+			got = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, &response{Error: "invalid admin token"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func listPlugins(w http.ResponseWriter, r *http.Request) {
+	infos := make([]*pluginInfo, 0)
+	for _, p := range plugin.Plugins() {
+		info := &pluginInfo{
+			Name:    p.String(),
+			State:   plugin.StateOf(p).String(),
+			Policy:  plugin.PolicyOf(p).String(),
+			Actions: make([]string, 0),
+		}
+		_, info.Health = p.(plugin.HealthChecker)
+		if a, ok := p.(plugin.Actioner); ok {
+			for name := range a.Actions() {
+				info.Actions = append(info.Actions, name)
+			}
+			sort.Strings(info.Actions)
+		}
+		infos = append(infos, info)
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func runAction(w http.ResponseWriter, r *http.Request) {
+	name, action := r.PathValue("name"), r.PathValue("action")
+	resp := &response{Plugin: name, Action: action}
+
+	var fn plugin.Action
+	for _, p := range plugin.Plugins() {
+		if p.String() != name {
+			continue
+		}
+		if a, ok := p.(plugin.Actioner); ok {
+			fn = a.Actions()[action]
+		}
+	}
+	if fn == nil {
+		resp.Error = "action not found"
+		writeJSON(w, http.StatusNotFound, resp)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		resp.Error = err.Error()
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	args := make(map[string]string, len(r.Form))
+	for k := range r.Form {
+		args[k] = r.Form.Get(k)
+	}
+
+	result, err := fn(r.Context(), args)
+	if err != nil {
+		log.Errorf("admin run %s/%s args:%v err:%v", name, action, args, err)
+		resp.Error = err.Error()
+		writeJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	log.Infof("admin run %s/%s args:%v done", name, action, args)
+	resp.Result = result
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/diycoder/elf/kit/grpool"
+	"github.com/diycoder/elf/plugin"
+)
+
+func TestHandler(t *testing.T) {
+	if err := plugin.Register(NewPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	h := Handler("secret")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/plugins", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/plugins", nil)
+	req.Header.Set(TokenHeader, "secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var infos []*pluginInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Name != "admin" || infos[0].Actions[0] != "grpool_tune" {
+		t.Fatalf("unexpected plugins %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/plugins/admin/grpool_tune", strings.NewReader("size=100"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || grpool.Cap() != 100 {
+		t.Fatalf("unexpected tune response %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/plugins/admin/unknown", nil)
+	req.Header.Set(TokenHeader, "secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/diycoder/elf/kit/grpool"
+	"github.com/diycoder/elf/plugin"
+	"github.com/diycoder/elf/plugin/log"
+
+	"github.com/urfave/cli/v2"
+)
+
+type admin struct {
+	server *http.Server
+
+	once    sync.Once
+	handler http.Handler
+}
+
+// Global Flags
+func (a *admin) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "admin_address",
+			Usage:   "Set the listen address of the admin server, e.g. \":9090\". Empty disables it.",
+			EnvVars: []string{"ADMIN_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:    "admin_token",
+			Usage:   "Set the token required by the admin server.",
+			EnvVars: []string{"ADMIN_TOKEN"},
+		},
+	}
+}
+
+// Sub-commands
+func (a *admin) Commands() []*cli.Command {
+	return nil
+}
+
+// Handle is the middleware handler for HTTP requests. We pass in
+// the existing handler so it can be wrapped to create a call chain.
+func (a *admin) Handler() plugin.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			// serve the request
+			h.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// Init starts the admin server on its own address, wrapped by the handler
+// chain of the other plugins. The chain is built on the first request, once
+// every plugin has been initialized.
+func (a *admin) Init(ctx *cli.Context) error {
+	address := ctx.String("admin_address")
+	if address == "" {
+		return nil
+	}
+	token := ctx.String("admin_token")
+	if token == "" {
+		return errors.New("admin token is empty")
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.once.Do(func() {
+			a.handler = a.chain(Handler(token))
+		})
+		a.handler.ServeHTTP(w, r)
+	})
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	a.server = &http.Server{Addr: address, Handler: h}
+	go func() {
+		if err := a.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Errorf("admin server listen %s err:%v", address, err)
+		}
+	}()
+	log.Infof("admin server listen on %s", address)
+	return nil
+}
+
+// chain wraps h with the handlers of the other active plugins.
+func (a *admin) chain(h http.Handler) http.Handler {
+	for _, p := range plugin.Active() {
+		if p == plugin.Plugin(a) {
+			continue
+		}
+		if wrap := p.Handler(); wrap != nil {
+			h = wrap(h)
+		}
+	}
+	return h
+}
+
+// Actions can be run through the admin plugin.
+func (a *admin) Actions() map[string]plugin.Action {
+	return map[string]plugin.Action{
+		"grpool_tune": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			size, err := strconv.Atoi(args["size"])
+			if err != nil || size <= 0 {
+				return nil, errors.New("invalid grpool size")
+			}
+			grpool.Tune(size)
+			return map[string]int{
+				"cap":     grpool.Cap(),
+				"running": grpool.Running(),
+				"free":    grpool.Free(),
+			}, nil
+		},
+	}
+}
+
+// Close shuts the admin server down.
+func (a *admin) Close(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+	return a.server.Shutdown(ctx)
+}
+
+// Name of the plugin
+func (a *admin) String() string {
+	return "admin"
+}
+
+func init() {
+	plugin.RegisterFactory("admin", NewPlugin)
+}
+
+func NewPlugin() plugin.Plugin {
+	return &admin{}
+}
@@ -73,6 +73,7 @@ func load(opts *Options) error {
 	if err := apolloConfig.Load(newSource(opts)); err != nil {
 		return err
 	}
+	cfg.Register("apollo", apolloConfig)
 	return nil
 }
 
@@ -1,12 +1,16 @@
 package apollo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 
 	"github.com/diycoder/elf/config"
 	"github.com/diycoder/elf/plugin"
+	nnet "github.com/diycoder/elf/utils/net"
 
 	"github.com/urfave/cli/v2"
 )
@@ -135,11 +139,47 @@ func (c *apollo) getConfigure(ctx *cli.Context) error {
 	return nil
 }
 
+// Check checks the apollo config service is reachable.
+func (c *apollo) Check(ctx context.Context) error {
+	if c.opts == nil {
+		return errors.New("apollo is not initialized")
+	}
+	u, err := url.Parse(c.opts.Address)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return nnet.Reachable(ctx, host)
+}
+
+// Actions can be run through the admin plugin.
+func (c *apollo) Actions() map[string]plugin.Action {
+	return map[string]plugin.Action{
+		"sync": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if apolloConfig == nil {
+				return nil, errors.New("apollo is not initialized")
+			}
+			return nil, Sync()
+		},
+	}
+}
+
 // Name of the plugin
 func (c *apollo) String() string {
 	return "apollo_config"
 }
 
+func init() {
+	plugin.RegisterFactory("apollo", NewPlugin)
+}
+
 func NewPlugin() plugin.Plugin {
 	c := &apollo{}
 	return c
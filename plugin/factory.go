@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a new instance of a plugin.
+type Factory func() Plugin
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+	// names maps a factory name to the name of the plugin it creates
+	names = make(map[string]string)
+)
+
+// RegisterFactory makes a plugin available by name, so it can be enabled
+// from a flag or config file. It is intended to be called from the init
+// function of the plugin package, and panics when the name is registered
+// twice or the factory is nil.
+func RegisterFactory(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("plugin: RegisterFactory factory is nil")
+	}
+	if _, ok := factories[name]; ok {
+		panic("plugin: RegisterFactory called twice for plugin " + name)
+	}
+	factories[name] = factory
+	names[name] = factory().String()
+}
+
+// Name returns the name of the plugin created by the factory registered
+// with name, or name itself when no factory is registered.
+func Name(name string) string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	if n, ok := names[name]; ok {
+		return n
+	}
+	return name
+}
+
+// Factories returns the sorted names of the registered factories.
+func Factories() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a plugin from the factory registered with name.
+func New(name string) (Plugin, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("plugin %s is not registered, forgot to import it?", name)
+	}
+	return factory(), nil
+}
@@ -0,0 +1,297 @@
+// Package health aggregates the health of elf plugins and user defined checks
+// and serves them as kubernetes liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diycoder/elf/plugin"
+)
+
+// Kind is the probe a check belongs to.
+type Kind int
+
+const (
+	// Readiness checks decide whether the service can receive traffic,
+	// e.g. store connections. Plugins implementing plugin.HealthChecker
+	// are readiness checks.
+	Readiness Kind = iota
+	// Liveness checks decide whether the process must be restarted. They
+	// are also part of the readiness probe.
+	Liveness
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+const (
+	defaultTimeout  = 3 * time.Second
+	defaultCacheTTL = time.Second
+)
+
+// CheckFunc reports nil when healthy.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Cached   bool   `json:"cached,omitempty"`
+}
+
+// Report is the aggregated outcome served on the probes.
+type Report struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Checks    []*Result `json:"checks"`
+}
+
+var ErrTimeout = errors.New("health check timeout")
+
+type check struct {
+	name string
+	kind Kind
+	fn   CheckFunc
+
+	mu     sync.Mutex
+	last   Result
+	expire time.Time
+}
+
+// Checker runs the registered checks with a per-check timeout and caches
+// each result for the configured ttl.
+type Checker struct {
+	sync.RWMutex
+	timeout time.Duration
+	ttl     time.Duration
+	checks  map[string]*check
+	plugins map[string]*check
+}
+
+var defaultChecker = NewChecker(defaultTimeout, defaultCacheTTL)
+
+// NewChecker creates a checker. A zero ttl disables caching.
+func NewChecker(timeout, ttl time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{
+		timeout: timeout,
+		ttl:     ttl,
+		checks:  make(map[string]*check),
+		plugins: make(map[string]*check),
+	}
+}
+
+// Register adds a named check to the checker.
+func (c *Checker) Register(name string, kind Kind, fn CheckFunc) error {
+	if name == "" {
+		return errors.New("health check name can not be empty")
+	}
+	if fn == nil {
+		return fmt.Errorf("health check %s func can not be nil", name)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.checks[name]; ok {
+		return fmt.Errorf("health check with name %s already registered", name)
+	}
+	c.checks[name] = &check{name: name, kind: kind, fn: fn}
+	return nil
+}
+
+// Deregister removes a named check.
+func (c *Checker) Deregister(name string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.checks, name)
+}
+
+// SetTimeout sets the timeout of a single check.
+func (c *Checker) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.timeout = d
+}
+
+// SetCacheTTL sets how long a check result is reused, zero disables caching.
+func (c *Checker) SetCacheTTL(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.ttl = d
+}
+
+// Run runs every check of the probe concurrently and aggregates the results.
+func (c *Checker) Run(ctx context.Context, kind Kind) *Report {
+	checks := c.collect(kind)
+
+	c.RLock()
+	timeout, ttl := c.timeout, c.ttl
+	c.RUnlock()
+
+	report := &Report{
+		Status:    StatusUp,
+		Timestamp: time.Now(),
+		Checks:    make([]*Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, ck := range checks {
+		wg.Add(1)
+		go func(i int, ck *check) {
+			defer wg.Done()
+			report.Checks[i] = ck.run(ctx, timeout, ttl)
+		}(i, ck)
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+			break
+		}
+	}
+	return report
+}
+
+// collect returns the checks of a probe sorted by name. Liveness checks are
+// part of the readiness probe, plugin checks only of the readiness probe.
+func (c *Checker) collect(kind Kind) []*check {
+	c.Lock()
+	defer c.Unlock()
+
+	checks := make([]*check, 0, len(c.checks))
+	for _, ck := range c.checks {
+		if kind == Readiness || ck.kind == kind {
+			checks = append(checks, ck)
+		}
+	}
+
+	if kind == Readiness {
+		for _, p := range plugin.Active() {
+			hc, ok := p.(plugin.HealthChecker)
+			if !ok {
+				continue
+			}
+			name := p.String()
+			if _, ok := c.checks[name]; ok {
+				continue
+			}
+			ck, ok := c.plugins[name]
+			if !ok {
+				ck = &check{name: name, kind: Readiness, fn: hc.Check}
+				c.plugins[name] = ck
+			}
+			checks = append(checks, ck)
+		}
+	}
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+	return checks
+}
+
+func (ck *check) run(ctx context.Context, timeout, ttl time.Duration) *Result {
+	ck.mu.Lock()
+	defer ck.mu.Unlock()
+
+	now := time.Now()
+	if ttl > 0 && now.Before(ck.expire) {
+		r := ck.last
+		r.Cached = true
+		return &r
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				errCh <- fmt.Errorf("health check panic: %v", e)
+			}
+		}()
+		errCh <- ck.fn(cctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-cctx.Done():
+		err = ErrTimeout
+	}
+
+	r := Result{
+		Name:     ck.name,
+		Status:   StatusUp,
+		Duration: time.Since(now).String(),
+	}
+	if err != nil {
+		r.Status = StatusDown
+		r.Error = err.Error()
+	}
+
+	ck.last = r
+	ck.expire = now.Add(ttl)
+	return &r
+}
+
+// Handler serves the report of a probe as json, with status 503 when down.
+func (c *Checker) Handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context(), kind)
+		code := http.StatusOK
+		if report.Status != StatusUp {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Register adds a named check to the default checker.
+func Register(name string, kind Kind, fn CheckFunc) error {
+	return defaultChecker.Register(name, kind, fn)
+}
+
+// Deregister removes a named check from the default checker.
+func Deregister(name string) {
+	defaultChecker.Deregister(name)
+}
+
+// Run runs the checks of a probe on the default checker.
+func Run(ctx context.Context, kind Kind) *Report {
+	return defaultChecker.Run(ctx, kind)
+}
+
+// LivenessHandler serves the liveness probe of the default checker.
+func LivenessHandler() http.Handler {
+	return defaultChecker.Handler(Liveness)
+}
+
+// ReadinessHandler serves the readiness probe of the default checker.
+func ReadinessHandler() http.Handler {
+	return defaultChecker.Handler(Readiness)
+}
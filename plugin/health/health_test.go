@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckerHandler(t *testing.T) {
+	c := NewChecker(50*time.Millisecond, 0)
+	if err := c.Register("alive", Liveness, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("db", Readiness, func(ctx context.Context) error { return errors.New("refused") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("db", Readiness, func(ctx context.Context) error { return nil }); err == nil {
+		t.Fatal("expected duplicate check error")
+	}
+
+	rec := httptest.NewRecorder()
+	c.Handler(Liveness).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("liveness code %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	c.Handler(Readiness).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness code %d", rec.Code)
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusDown || len(report.Checks) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if r := report.Checks[1]; r.Name != "db" || r.Error != "refused" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+}
+
+func TestCheckerTimeoutAndCache(t *testing.T) {
+	var calls int32
+	c := NewChecker(20*time.Millisecond, time.Minute)
+	_ = c.Register("slow", Readiness, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+
+	report := c.Run(context.Background(), Readiness)
+	if report.Status != StatusDown || report.Checks[0].Error != ErrTimeout.Error() {
+		t.Fatalf("expected timeout, got %+v", report.Checks[0])
+	}
+
+	report = c.Run(context.Background(), Readiness)
+	if !report.Checks[0].Cached || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected cached result, got %+v calls %d", report.Checks[0], calls)
+	}
+}
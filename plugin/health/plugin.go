@@ -0,0 +1,102 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/diycoder/elf/plugin"
+
+	"github.com/urfave/cli/v2"
+)
+
+type health struct {
+	livenessPath  string
+	readinessPath string
+}
+
+const (
+	defaultLivenessPath  = "/healthz"
+	defaultReadinessPath = "/readyz"
+)
+
+// Global Flags
+func (h *health) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "health_liveness_path",
+			Value:   defaultLivenessPath,
+			Usage:   "Set the http path of the liveness probe.",
+			EnvVars: []string{"HEALTH_LIVENESS_PATH"},
+		},
+		&cli.StringFlag{
+			Name:    "health_readiness_path",
+			Value:   defaultReadinessPath,
+			Usage:   "Set the http path of the readiness probe.",
+			EnvVars: []string{"HEALTH_READINESS_PATH"},
+		},
+		&cli.DurationFlag{
+			Name:    "health_timeout",
+			Value:   defaultTimeout,
+			Usage:   "Set the timeout of a single health check, e.g. \"3s\".",
+			EnvVars: []string{"HEALTH_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "health_cache_ttl",
+			Value:   defaultCacheTTL,
+			Usage:   "Set how long a health check result is cached, \"0\" disables caching.",
+			EnvVars: []string{"HEALTH_CACHE_TTL"},
+		},
+	}
+}
+
+// Sub-commands
+func (h *health) Commands() []*cli.Command {
+	return nil
+}
+
+// Handle is the middleware handler for HTTP requests. Requests on the probe
+// paths are answered by the default checker, others are passed through.
+func (h *health) Handler() plugin.Handler {
+	return func(next http.Handler) http.Handler {
+		liveness := LivenessHandler()
+		readiness := ReadinessHandler()
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case h.livenessPath:
+				liveness.ServeHTTP(rw, r)
+			case h.readinessPath:
+				readiness.ServeHTTP(rw, r)
+			default:
+				next.ServeHTTP(rw, r)
+			}
+		})
+	}
+}
+
+// Init called when command line args are parsed.
+func (h *health) Init(ctx *cli.Context) error {
+	if p := ctx.String("health_liveness_path"); p != "" {
+		h.livenessPath = p
+	}
+	if p := ctx.String("health_readiness_path"); p != "" {
+		h.readinessPath = p
+	}
+	defaultChecker.SetTimeout(ctx.Duration("health_timeout"))
+	defaultChecker.SetCacheTTL(ctx.Duration("health_cache_ttl"))
+	return nil
+}
+
+// Name of the plugin
+func (h *health) String() string {
+	return "health"
+}
+
+func init() {
+	plugin.RegisterFactory("health", NewPlugin)
+}
+
+func NewPlugin() plugin.Plugin {
+	return &health{
+		livenessPath:  defaultLivenessPath,
+		readinessPath: defaultReadinessPath,
+	}
+}
@@ -0,0 +1,292 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/plugin/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultAccessBodyLimit = 1024
+	// defaultTrustedProxies are the loopback and private networks
+	defaultTrustedProxies = "127.0.0.0/8,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,::1/128,fc00::/7"
+)
+
+// Access configures the access lines the plugin handler writes with the
+// "access" type, one per request.
+type Access struct {
+	Disable bool
+	// Exclude are path.Match patterns of the paths not logged, a trailing
+	// "/**" matches the sub paths too, e.g. "/health/**"
+	Exclude []string
+	// Slow escalates the requests taking longer to warn, 0 disables
+	Slow time.Duration
+	// Body captures the request and response bodies up to BodyLimit bytes
+	Body      bool
+	BodyLimit int
+	// TrustedProxies are the networks of the proxies whose X-Forwarded-For
+	// and X-Real-Ip headers are trusted, nil trusts none
+	TrustedProxies []*net.IPNet
+}
+
+// defaultAccess is set by the log_access flags in Init
+var defaultAccess = &Access{Disable: true}
+
+func (a *Access) excluded(p string) bool {
+	for _, pattern := range a.Exclude {
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessHandler writes an access line after next serves each request, the
+// settings of a are read per request. The request id is set before, so the
+// access line carries the id the request id middleware keeps later on.
+func AccessHandler(a *Access, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return requestid.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if a.Disable || a.excluded(r.URL.Path) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		start := time.Now()
+		w := &accessWriter{ResponseWriter: rw, status: http.StatusOK}
+		var reqBody *limitBuffer
+		if a.Body {
+			limit := a.BodyLimit
+			if limit <= 0 {
+				limit = defaultAccessBodyLimit
+			}
+			w.body = &limitBuffer{limit: limit}
+			if r.Body != nil && r.Body != http.NoBody {
+				reqBody = &limitBuffer{limit: limit}
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, reqBody), r.Body}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+		latency := time.Since(start)
+
+		kv := []interface{}{
+			"method", r.Method,
+			"host", r.Host,
+			"path", r.URL.Path,
+			"query", r.URL.RawQuery,
+			"proto", r.Proto,
+			"status", w.status,
+			"bytes", w.bytes,
+			"latency_ms", float64(latency.Microseconds()) / 1e3,
+			"client_ip", a.ClientIP(r),
+			"user_agent", r.UserAgent(),
+		}
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			if id := traceIDOf(r.Header.Get("traceparent")); id != "" {
+				kv = append(kv, nglog.TraceIDKey, id)
+			}
+		}
+		if reqBody != nil {
+			kv = append(kv, "req_body", reqBody.String())
+		}
+		if w.body != nil {
+			kv = append(kv, "resp_body", w.body.String())
+		}
+
+		l := defaultLog.WithContext(r.Context()).WithField("type", "access")
+		if a.Slow > 0 && latency >= a.Slow {
+			l.Warnw("slow request", kv...)
+			return
+		}
+		l.Infow("access", kv...)
+	}))
+}
+
+// ClientIP returns the client address of r with the trusted proxies of the
+// log_access flags, see Access.ClientIP.
+func ClientIP(r *http.Request) string {
+	return defaultAccess.ClientIP(r)
+}
+
+// ClientIP returns the remote address of r. When it is a trusted proxy, it
+// returns the right-most address of X-Forwarded-For which is not a trusted
+// proxy, or X-Real-Ip without X-Forwarded-For, the addresses left of it may
+// be sent by the client.
+func (a *Access) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !a.trusted(host) {
+		return host
+	}
+
+	if xffs := r.Header.Values("X-Forwarded-For"); len(xffs) > 0 {
+		hops := strings.Split(strings.Join(xffs, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			host = hop
+			if !a.trusted(hop) {
+				break
+			}
+		}
+		return host
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-Ip")); ip != "" {
+		return ip
+	}
+	return host
+}
+
+func (a *Access) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses the comma separated addresses or CIDRs in s.
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// traceIDOf returns the trace id of a W3C traceparent header.
+func traceIDOf(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := trace.TraceIDFromHex(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+// accessWriter records the status, the size and the head of the body.
+type accessWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+	body        *limitBuffer
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	if w.body != nil {
+		w.body.Write(p[:n])
+	}
+	return n, err
+}
+
+func (w *accessWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		conn, rw, err := h.Hijack()
+		if err == nil {
+			// the response is written on the connection
+			w.wroteHeader = true
+		}
+		return conn, rw, err
+	}
+	return nil, nil, fmt.Errorf("response writer does not support hijacking")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// limitBuffer keeps the first limit bytes written to it.
+type limitBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - b.buf.Len(); n < len(p) {
+		b.truncated = true
+		if n > 0 {
+			b.buf.Write(p[:n])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "..."
+	}
+	return b.buf.String()
+}
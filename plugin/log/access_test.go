@@ -0,0 +1,130 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diycoder/elf/plugin/requestid"
+)
+
+func TestAccessHandler(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{Name: "access", Output: "file", Filename: "access.log", Encoder: "json", NoDefaultFields: true}},
+		Routes:  map[string]string{"access": "access"},
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := defaultLog
+	SetLogger(pl)
+	defer SetLogger(old)
+
+	proxies, err := parseTrustedProxies(defaultTrustedProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Access{
+		TrustedProxies: proxies,
+		Exclude:        []string{"/health/**", "/metrics"},
+		Slow:           50 * time.Millisecond,
+		Body:           true,
+		BodyLimit:      8,
+	}
+	// the request id middleware runs after the log plugin handler
+	h := AccessHandler(a, requestid.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/slow" {
+			time.Sleep(60 * time.Millisecond)
+		}
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte("echo:"))
+		rw.Write(b)
+	})))
+
+	for _, path := range []string{"/health/live", "/metrics"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/users?id=1", strings.NewReader("hello world"))
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(requestid.Header(), "rid-1")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	if rw.Code != http.StatusCreated || rw.Body.String() != "echo:hello world" {
+		t.Fatalf("response %d %q", rw.Code, rw.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/slow", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set("X-Real-Ip", "5.6.7.8")
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	id := rw.Header().Get(requestid.Header())
+
+	lines := strings.Split(strings.TrimSpace(readLog(t, filepath.Join(dir, "access.log"))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 access lines, got %q", lines)
+	}
+	for _, want := range []string{
+		`"level":"INFO"`, `"method":"POST"`, `"path":"/users"`, `"query":"id=1"`, `"status":201`,
+		`"bytes":16`, `"client_ip":"1.2.3.4"`, `"request_id":"rid-1"`,
+		`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`, `"req_body":"hello wo..."`, `"resp_body":"echo:hel..."`,
+	} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("want %s in %s", want, lines[0])
+		}
+	}
+	for _, want := range []string{`"level":"WARN"`, `"msg":"slow request"`, `"client_ip":"5.6.7.8"`, `"status":201`, `"request_id":"` + id + `"`} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("want %s in %s", want, lines[1])
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Access{TrustedProxies: proxies}
+	for _, c := range []struct {
+		remote, xff, realIP, want string
+	}{
+		// the client prepends a forged hop
+		{"10.0.0.2:80", "6.6.6.6, 1.2.3.4, 10.0.0.1", "", "1.2.3.4"},
+		{"10.0.0.2:80", "10.0.0.3, 10.0.0.1", "", "10.0.0.3"},
+		{"127.0.0.1:80", "", "5.6.7.8", "5.6.7.8"},
+		// the headers of an untrusted peer are ignored
+		{"9.9.9.9:80", "1.2.3.4", "5.6.7.8", "9.9.9.9"},
+		{"[::1]:80", "1.2.3.4", "", "::1"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = c.remote
+		if c.xff != "" {
+			r.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if c.realIP != "" {
+			r.Header.Set("X-Real-Ip", c.realIP)
+		}
+		if got := a.ClientIP(r); got != c.want {
+			t.Errorf("%+v: client ip %s", c, got)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/8,proxy"); err == nil {
+		t.Fatal("want an invalid address")
+	}
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/kit/log/alert"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// defaultHooks are handed the entries of the loggers built by Init
+	defaultHooks []nglog.Hook
+	// defaultAlerter is set by the log_alert flags
+	defaultAlerter *alert.Alerter
+)
+
+// AddHook hands the entries of the loggers to h from the next Init on, e.g.
+// an alert.Alerter.
+func AddHook(h nglog.Hook) {
+	defaultHooks = append(defaultHooks, h)
+}
+
+func hooks() []nglog.Hook {
+	hs := append([]nglog.Hook(nil), defaultHooks...)
+	if defaultAlerter != nil {
+		hs = append(hs, defaultAlerter)
+	}
+	return hs
+}
+
+// newAlerter creates the alerter of the log_alert flags, nil without a
+// target.
+func newAlerter(ctx *cli.Context) (*alert.Alerter, error) {
+	target := ctx.String("log_alert_target")
+	if target == "" {
+		return nil, nil
+	}
+
+	var n alert.Notifier
+	switch typ := ctx.String("log_alert_type"); typ {
+	case "", "webhook":
+		wh, err := alert.NewWebhook(target, ctx.String("log_alert_body"))
+		if err != nil {
+			return nil, fmt.Errorf("[log] invalid log_alert_body: %v", err)
+		}
+		n = wh
+	case "dingtalk":
+		n = alert.NewDingTalk(target, ctx.String("log_alert_secret"))
+	case "feishu":
+		n = alert.NewFeishu(target, ctx.String("log_alert_secret"))
+	case "slack":
+		n = alert.NewSlack(target)
+	case "command":
+		args := strings.Fields(target)
+		n = alert.NewCommand(args[0], args[1:]...)
+	default:
+		return nil, fmt.Errorf("[log] invalid log_alert_type %q", typ)
+	}
+
+	opts := []alert.Option{
+		alert.WithNotifier(n),
+		// the warn level is not alerted by default, errors would loop
+		alert.WithOnError(func(err error) {
+			Warnf("[log] alert: %v", err)
+		}),
+	}
+	if name := ctx.String("log_alert_level"); name != "" {
+		lv, err := nglog.ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("[log] invalid log_alert_level %q", name)
+		}
+		opts = append(opts, alert.WithLevel(lv))
+	}
+	if window := ctx.String("log_alert_window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return nil, fmt.Errorf("[log] invalid log_alert_window %q", window)
+		}
+		opts = append(opts, alert.WithWindow(d))
+	}
+	return alert.New(opts...)
+}
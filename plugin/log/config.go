@@ -6,9 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	nglog "github.com/diycoder/elf/kit/log"
 	"github.com/diycoder/elf/kit/log/writer/rotate"
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
 	"github.com/diycoder/elf/kit/runenv"
 	"github.com/diycoder/elf/utils/net"
 )
@@ -18,10 +20,31 @@ var (
 	defaultLogLevel      = nglog.DebugLevel
 	defaultRoateDuration = rotate.DefaultRotateDuration
 	defaultFileMode      = rotate.DefaultFileMode
-	defaultProjectName   = filepath.Base(os.Args[0])
-	defaultLogFileName   = fmt.Sprint(defaultProjectName, ".log")
-	defaultHostName      = getHost()
-	defaultLogMod        = getLogMod()
+	// defaultMaxSize is the size in bytes the log files roll over at, 0 disables
+	defaultMaxSize int64
+	// defaultCompressor compresses the rotated log files
+	defaultCompressor rotatelogs.Compressor
+	// defaultArchiveDir is where the rotated log files are moved to, per sub dir
+	defaultArchiveDir  string
+	defaultProjectName = filepath.Base(os.Args[0])
+	defaultLogFileName = fmt.Sprint(defaultProjectName, ".log")
+	defaultHostName    = getHost()
+	defaultLogMod      = getLogMod()
+	// defaultAsync applies to the pipeline loggers without Async
+	defaultAsync *Async
+	// defaultLogSink is the url the logs are shipped to in the sink mod
+	defaultLogSink string
+	// defaultSinkAsync applies to the sink loggers without Async, so a log
+	// call never waits for the network
+	defaultSinkAsync = &Async{Policy: "drop_newest"}
+
+	// defaultLocation names and rotates the log files
+	defaultLocation      = rotate.DefaultLocation
+	defaultRotatePattern = rotate.DefaultRotatePattern
+	// defaultTimeLocation converts the logged times, nil keeps time.Local
+	defaultTimeLocation *time.Location
+	defaultTimeEncoder  = nglog.ChinaMilliTimeEncoder
+	defaultTimeLayout   string
 )
 
 const (
@@ -31,6 +54,7 @@ const (
 	outTerminal          = 0
 	outFile              = 1
 	outTerminalAndFile   = 2
+	outSink              = 3
 	defaultOutTerminal   = outTerminal
 )
 
@@ -90,3 +114,14 @@ func GetLogDir() string {
 func getDefaultLogFilename() string {
 	return defaultLogFileName
 }
+
+// parseLocation loads the time zone name, "UTC" and "Local" included.
+func parseLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "utc":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
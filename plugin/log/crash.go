@@ -0,0 +1,157 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+)
+
+// crashSubDir is the dir under the log dir of the crash reports and the
+// goroutine dumps
+const crashSubDir = "crash"
+
+var (
+	// exit is replaced in tests
+	exit = os.Exit
+	// defaultRecover recovers the panics of the plugin handler
+	defaultRecover = true
+)
+
+// crash writes a crash report of reason, sends the pending alerts, flushes
+// and closes all the loggers and exits, the entry of reason is written
+// already.
+func crash(reason string) {
+	writeCrash(reason, debug.Stack())
+	if defaultAlerter != nil {
+		_ = defaultAlerter.Close()
+	}
+	_ = Close()
+	exit(1)
+}
+
+// writeCrash writes a crash report, or reason to stderr when it fails.
+func writeCrash(reason string, stack []byte) {
+	if _, err := WriteCrashReport(reason, stack); err != nil {
+		fmt.Fprintf(os.Stderr, "[log] crash report: %v\n%s\n", err, reason)
+	}
+}
+
+// WriteCrashReport writes reason, the stack of the crashing goroutine and the
+// stacks of all the goroutines to a file in the crash dir of the log dir, it
+// returns the path of the file.
+func WriteCrashReport(reason string, stack []byte) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "project: %s\nhost: %s\npid: %d\ngo: %s\n", defaultProjectName, defaultHostName, os.Getpid(), runtime.Version())
+	fmt.Fprintf(&b, "reason: %s\n\n%s\n", reason, stack)
+	b.WriteString("all goroutines:\n\n")
+	_ = pprof.Lookup("goroutine").WriteTo(&b, 2)
+	return writeCrashFile("crash", b.Bytes())
+}
+
+// DumpGoroutines writes the stacks of all the goroutines to a file in the
+// crash dir of the log dir, it returns the path of the file.
+func DumpGoroutines() (string, error) {
+	var b bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&b, 2)
+	return writeCrashFile("goroutines", b.Bytes())
+}
+
+// writeCrashFile writes b to "project.time.pid.ext" in the crash dir.
+func writeCrashFile(ext string, b []byte) (string, error) {
+	dir := filepath.Join(defaultLogDir, crashSubDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s.%s.%d.%s", defaultProjectName, time.Now().Format("20060102150405.000"), os.Getpid(), ext)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, b, defaultFileMode); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Recover logs a panic of the goroutine with its stack, writes a crash
+// report, flushes the loggers and panics again, e.g. defer log.Recover().
+// The loggers are left open, the panic may be recovered further up.
+func Recover() {
+	if p := recover(); p != nil {
+		stack := debug.Stack()
+		Errorw("[log] panic", "panic", fmt.Sprint(p), "stack", string(stack))
+		writeCrash(fmt.Sprintf("panic: %v", p), stack)
+		_ = Sync()
+		panic(p)
+	}
+}
+
+// RecoverHandler recovers the panics of next, logs them with the stack and
+// replies 500 unless the response was written already. http.ErrAbortHandler
+// is passed on to abort the response.
+func RecoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w := &accessWriter{ResponseWriter: rw, status: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			defaultLog.WithContext(r.Context()).Errorw("[log] handler panic",
+				"panic", fmt.Sprint(p),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"stack", string(debug.Stack()),
+			)
+			if !w.wroteHeader {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logTaskPanic logs the panics of the grpool tasks with their stack.
+func logTaskPanic(p interface{}, stack []byte) {
+	Errorw("[grpool] task panic", "panic", fmt.Sprint(p), "stack", string(stack))
+}
+
+// handleDumpSignal writes a goroutine dump on SIGQUIT instead of exiting
+// until the returned stop function is called.
+func handleDumpSignal() (func() error, error) {
+	ch := make(chan os.Signal, 1)
+	if err := notifyDumpSignal(ch); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				path, err := DumpGoroutines()
+				if err != nil {
+					Errorf("[log] %v: %v", sig, err)
+					continue
+				}
+				Warnw("[log] goroutines dumped", "signal", sig.String(), "file", path)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		signal.Stop(ch)
+		close(done)
+		return nil
+	}, nil
+}
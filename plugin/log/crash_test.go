@@ -0,0 +1,156 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/kit/log/logtest"
+)
+
+func TestFatal(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	code := -1
+	defer func() { exit = os.Exit }()
+	exit = func(c int) { code = c }
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{
+			Name:     "error",
+			Output:   "file",
+			Filename: "error.log",
+			Async:    &Async{FlushInterval: "1h"},
+		}},
+		Routes: map[string]string{"error": "error"},
+	}
+	pl, closers, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	closersMu.Lock()
+	activeClosers = closers
+	closersMu.Unlock()
+
+	pl.Fatalf("cannot listen on %d", 8080)
+	if code != 1 {
+		t.Fatalf("exit code %d", code)
+	}
+	// the async writer is flushed before exiting
+	if got := readLog(t, filepath.Join(dir, "error.log")); !strings.Contains(got, "cannot listen on 8080") || !strings.Contains(got, "crash_test.go") {
+		t.Fatalf("error log %q", got)
+	}
+
+	reports, err := filepath.Glob(filepath.Join(dir, crashSubDir, "*.crash"))
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("crash reports %v: %v", reports, err)
+	}
+	report := readLog(t, reports[0])
+	for _, want := range []string{"reason: fatal: cannot listen on 8080", "TestFatal", "all goroutines:"} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("crash report without %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{
+			Name:     "error",
+			Output:   "file",
+			Filename: "error.log",
+			Async:    &Async{FlushInterval: "1h"},
+		}},
+		Routes: map[string]string{"error": "error"},
+	}
+	pl, closers, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(closers)
+	old := GetLogger()
+	SetLogger(pl)
+	defer SetLogger(old)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("recovered %v", p)
+			}
+		}()
+		defer Recover()
+		panic("boom")
+	}()
+	if got := readLog(t, filepath.Join(dir, "error.log")); !strings.Contains(got, "[log] panic") {
+		t.Fatalf("error log %q", got)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(dir, crashSubDir, "*.crash")); len(reports) != 1 {
+		t.Fatalf("crash reports %v", reports)
+	}
+
+	// the panic is recovered further up, the loggers are still open
+	pl.Error("after recover")
+	if err := pl.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readLog(t, filepath.Join(dir, "error.log")); !strings.Contains(got, "after recover") {
+		t.Fatalf("error log %q", got)
+	}
+}
+
+func TestRecoverHandler(t *testing.T) {
+	lt := logtest.New()
+	old := GetLogger()
+	SetLogger(lt)
+	defer SetLogger(old)
+
+	h := RecoverHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++
+	}))
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rw.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d", rw.Code)
+	}
+	lt.AssertLogged(t, nglog.ErrorLevel, "handler panic", map[string]interface{}{"path": "/users"})
+	e := lt.Filter(nglog.ErrorLevel, "handler panic", nil)[0]
+	if stack := e.Fields["stack"].(string); !strings.Contains(stack, "crash_test.go") {
+		t.Fatalf("stack %s", stack)
+	}
+
+	// a response written already is kept
+	partial := RecoverHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte("partial"))
+		panic("boom")
+	}))
+	rw = httptest.NewRecorder()
+	partial.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rw.Code != http.StatusAccepted || rw.Body.String() != "partial" {
+		t.Fatalf("response %d %q", rw.Code, rw.Body.String())
+	}
+
+	// the aborted responses are passed on
+	abort := RecoverHandler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("recovered %v", p)
+		}
+	}()
+	abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
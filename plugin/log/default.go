@@ -7,24 +7,44 @@ import (
 var defaultLog nglog.Logger = NewPLogger()
 
 var (
-	Debug      = defaultLog.Debug
-	Debugf     = defaultLog.Debugf
-	Trace      = defaultLog.Trace
-	Tracef     = defaultLog.Tracef
-	Info       = defaultLog.Info
-	Infof      = defaultLog.Infof
-	Warn       = defaultLog.Warn
-	Warnf      = defaultLog.Warnf
-	Error      = defaultLog.Error
-	Errorf     = defaultLog.Errorf
-	Panic      = defaultLog.Panic
-	Panicf     = defaultLog.Panicf
-	Fatal      = defaultLog.Fatal
-	Fatalf     = defaultLog.Fatalf
-	WithField  = defaultLog.WithField
-	WithFields = defaultLog.WithFields
+	Debug       = defaultLog.Debug
+	Debugf      = defaultLog.Debugf
+	Trace       = defaultLog.Trace
+	Tracef      = defaultLog.Tracef
+	Info        = defaultLog.Info
+	Infof       = defaultLog.Infof
+	Warn        = defaultLog.Warn
+	Warnf       = defaultLog.Warnf
+	Error       = defaultLog.Error
+	Errorf      = defaultLog.Errorf
+	Panic       = defaultLog.Panic
+	Panicf      = defaultLog.Panicf
+	Fatal       = defaultLog.Fatal
+	Fatalf      = defaultLog.Fatalf
+	Debugw      = defaultLog.Debugw
+	Infow       = defaultLog.Infow
+	Warnw       = defaultLog.Warnw
+	Errorw      = defaultLog.Errorw
+	DebugCtx    = defaultLog.DebugCtx
+	DebugfCtx   = defaultLog.DebugfCtx
+	InfoCtx     = defaultLog.InfoCtx
+	InfofCtx    = defaultLog.InfofCtx
+	WarnCtx     = defaultLog.WarnCtx
+	WarnfCtx    = defaultLog.WarnfCtx
+	ErrorCtx    = defaultLog.ErrorCtx
+	ErrorfCtx   = defaultLog.ErrorfCtx
+	WithField   = defaultLog.WithField
+	WithFields  = defaultLog.WithFields
+	WithContext = defaultLog.WithContext
+	With        = defaultLog.With
+	Sync        = defaultLog.Sync
 )
 
+// GetLogger returns the logger set by SetLogger or Init.
+func GetLogger() nglog.Logger {
+	return defaultLog
+}
+
 func SetLogger(logger nglog.Logger) {
 	defaultLog = logger
 	Debug = defaultLog.Debug
@@ -41,6 +61,21 @@ func SetLogger(logger nglog.Logger) {
 	Panicf = defaultLog.Panicf
 	Fatal = defaultLog.Fatal
 	Fatalf = defaultLog.Fatalf
+	Debugw = defaultLog.Debugw
+	Infow = defaultLog.Infow
+	Warnw = defaultLog.Warnw
+	Errorw = defaultLog.Errorw
+	DebugCtx = defaultLog.DebugCtx
+	DebugfCtx = defaultLog.DebugfCtx
+	InfoCtx = defaultLog.InfoCtx
+	InfofCtx = defaultLog.InfofCtx
+	WarnCtx = defaultLog.WarnCtx
+	WarnfCtx = defaultLog.WarnfCtx
+	ErrorCtx = defaultLog.ErrorCtx
+	ErrorfCtx = defaultLog.ErrorfCtx
 	WithField = defaultLog.WithField
 	WithFields = defaultLog.WithFields
+	WithContext = defaultLog.WithContext
+	With = defaultLog.With
+	Sync = defaultLog.Sync
 }
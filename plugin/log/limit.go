@@ -0,0 +1,122 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"golang.org/x/time/rate"
+)
+
+// limitSummaryInterval is the interval the summaries of the entries dropped
+// by a rate limit are written at.
+var limitSummaryInterval = 10 * time.Second
+
+// RateLimit limits the entries of a type to Rate per second, allowing bursts
+// of Burst entries.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// limiter is a token bucket counting the entries it dropped.
+type limiter struct {
+	bucket  *rate.Limiter
+	dropped uint64
+
+	// logger and level of the last dropped entry, the summary goes to the
+	// same route
+	mu     sync.Mutex
+	logger nglog.Logger
+	level  nglog.Level
+}
+
+func newLimiters(limits map[string]RateLimit) (map[string]*limiter, error) {
+	limiters := make(map[string]*limiter, len(limits))
+	for typ, l := range limits {
+		if l.Rate <= 0 || l.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit of %s: rate and burst must be positive", typ)
+		}
+		limiters[typ] = &limiter{
+			bucket: rate.NewLimiter(rate.Limit(l.Rate), l.Burst),
+		}
+	}
+	return limiters, nil
+}
+
+// allow reports whether an entry of lv written to l may be written.
+func (l *limiter) allow(logger nglog.Logger, lv nglog.Level) bool {
+	if l.bucket.Allow() {
+		return true
+	}
+	l.mu.Lock()
+	l.logger, l.level = logger, lv
+	l.mu.Unlock()
+	atomic.AddUint64(&l.dropped, 1)
+	return false
+}
+
+// flush writes the summary of the entries dropped since the last one.
+func (l *limiter) flush(key string) {
+	n := atomic.SwapUint64(&l.dropped, 0)
+	if n == 0 {
+		return
+	}
+	l.mu.Lock()
+	logger, lv := l.logger, l.level
+	l.mu.Unlock()
+	logDropped(logger, lv, key, n)
+}
+
+func flushLimiters(limiters map[string]*limiter) {
+	for key, l := range limiters {
+		l.flush(key)
+	}
+}
+
+// dropFlusher writes the summaries of the dropped entries periodically until
+// it is closed.
+type dropFlusher struct {
+	mu     sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// start runs flush every limitSummaryInterval, unless it is running or closed.
+func (f *dropFlusher) start(flush func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed || f.stop != nil {
+		return
+	}
+	f.stop, f.done = make(chan struct{}), make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		t := time.NewTicker(limitSummaryInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				flush()
+			case <-stop:
+				return
+			}
+		}
+	}(f.stop, f.done)
+}
+
+func (f *dropFlusher) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	if f.stop != nil {
+		close(f.stop)
+		<-f.done
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"io"
 	slog "log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	nglog "github.com/diycoder/elf/kit/log"
 	"github.com/diycoder/elf/kit/log/writer/rotate"
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
 )
 
 func Init(cfg map[string]string) error {
@@ -22,343 +25,98 @@ func Init(cfg map[string]string) error {
 		_ = SetLogDir(path)
 	}
 
-	builders := []builder{
-		newDebugZapLogger,
-		newTraceZapLogger,
-		newInfoZapLogger,
-		newWarnZapLogger,
-		newErrorZapLogger,
-		newPanicZapLogger,
-		newFatalZapLogger,
-		newAccessZapLogger,
-		newTracingZapLogger,
-		newMicroZapLogger,
-	}
-
-	pl := NewPLogger()
-	for _, builder := range builders {
-		var (
-			err error
-			typ string
-			zl  nglog.Logger
-		)
-
-		if typ, zl, err = builder(defaultLogMod); err != nil {
-			return err
-		}
-		if err = pl.Register(typ, zl); err != nil {
-			return err
-		}
+	pl, closers, err := GetPipeline().build()
+	if err != nil {
+		return err
 	}
+	// the logger writes the pending rate limit summaries before its writers
+	// are closed
+	closers = append([]io.Closer{pl}, closers...)
+	old := defaultLog
 	SetLogger(pl)
+	// slog.SetDefault redirects the log package too, it is replaced after
+	replaceSlogLogger(pl)
 	replaceSysLogger()
+
+	// the loggers derived from the replaced one may still be in use, its
+	// writers are flushed and closed by Close only
+	_ = old.Sync()
+	closersMu.Lock()
+	retiredClosers = append(retiredClosers, activeClosers...)
+	activeClosers = closers
+	activeFiles = pl.files
+	closersMu.Unlock()
 	return nil
 }
 
-type builder func(int int) (string, nglog.Logger, error)
+var (
+	closersMu     sync.Mutex
+	activeClosers []io.Closer
+	// retiredClosers are the writers of the loggers replaced by Init
+	retiredClosers []io.Closer
+	// activeFiles are reopened and rotated on signals
+	activeFiles []*rotatelogs.RotateLogs
+)
+
+// Close flushes the loggers and closes the async writers, it should be
+// called before the process exits. Logging after Close fails.
+func Close() error {
+	err := Sync()
+	closersMu.Lock()
+	closers := append(activeClosers, retiredClosers...)
+	activeClosers, retiredClosers = nil, nil
+	activeFiles = nil
+	closersMu.Unlock()
+	if cerr := closeAll(closers); err == nil {
+		err = cerr
+	}
+	return err
+}
 
-func newWriter(mod int, subDir, filename string) (io.Writer, error) {
+// newWriter creates the writer of mod, file is the rotating file it writes to
+// if any, so that it can be reopened and rotated on signals.
+func newWriter(mod int, subDir, filename, sink string) (w io.Writer, file *rotatelogs.RotateLogs, err error) {
+	if mod == outSink {
+		w, err = newSinkWriter(sink)
+		return w, nil, err
+	}
 	if mod == outTerminal {
 		r := io.MultiWriter(os.Stdout)
-		return r, nil
+		return r, nil, nil
 	}
 	if mod == outFile {
-		r, err := rotate.NewWriter(
-			rotate.WithLogDir(defaultLogDir),
-			rotate.WithLogSubDir(subDir),
-			rotate.WithFileMode(defaultFileMode),
-			rotate.WithFilename(filename),
-			rotate.WithDuration(defaultRoateDuration),
-		)
+		r, err := rotate.NewRotateLogger(rotateConfig(subDir, filename))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		return r, nil
+		return r, r, nil
 	}
 	if mod == outTerminalAndFile {
-		r, err := rotate.NewWriter(
-			rotate.WithLogDir(defaultLogDir),
-			rotate.WithLogSubDir(subDir),
-			rotate.WithFileMode(defaultFileMode),
-			rotate.WithFilename(filename),
-			rotate.WithDuration(defaultRoateDuration),
-		)
+		r, err := rotate.NewRotateLogger(rotateConfig(subDir, filename))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		r = io.MultiWriter(os.Stdout, r)
-		return r, nil
-	}
-	return nil, nil
-}
-
-func newAccessZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ      = "access"
-		subDir   = "info/"
-		filename = "gw-access.log"
-	)
-
-	r, err := newWriter(mod, subDir, filename)
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newDebugZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "debug"
-		subDir = "debug/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newInfoZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "info"
-		subDir = "info/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newWarnZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "warn"
-		subDir = "warn/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.AddCaller(),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newTraceZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "trace"
-		subDir = "debug/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newErrorZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "error"
-		subDir = "error/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddStacktrace(defaultLogLevel),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newPanicZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "panic"
-		subDir = "error/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddStacktrace(defaultLogLevel),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
-}
-
-func newFatalZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ    = "fatal"
-		subDir = "error/"
-	)
-
-	r, err := newWriter(mod, subDir, getDefaultLogFilename())
-	if err != nil {
-		return "", nil, err
-	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddStacktrace(defaultLogLevel),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	if err != nil {
-		return "", nil, err
-	}
-
-	return typ, zl, nil
-}
-
-func newMicroZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ      = "micro"
-		subDir   = "info/"
-		filename = "micro.log"
-	)
-
-	r, err := newWriter(mod, subDir, filename)
-	if err != nil {
-		return "", nil, err
+		return io.MultiWriter(os.Stdout, r), r, nil
 	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.Fields(defaultZapFields()),
-			nglog.WithEncoder(defaultLogEncoder),
-			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
+	return nil, nil, nil
 }
 
-func newTracingZapLogger(mod int) (string, nglog.Logger, error) {
-	const (
-		typ      = "tracing"
-		subDir   = "info/"
-		filename = "tracing.log"
-	)
-
-	r, err := newWriter(mod, subDir, filename)
-	if err != nil {
-		return "", nil, err
+func rotateConfig(subDir, filename string) *rotate.Config {
+	cfg := rotate.NewWriterConfig()
+	cfg.Dir = defaultLogDir
+	cfg.Sub = subDir
+	cfg.Perm = defaultFileMode
+	cfg.Filename = filename
+	cfg.Duration = defaultRoateDuration
+	cfg.Loc = defaultLocation
+	cfg.Pattern = defaultRotatePattern
+	cfg.MaxSize = defaultMaxSize
+	cfg.Compressor = defaultCompressor
+	if defaultArchiveDir != "" {
+		// the sub dirs hold files of the same name
+		cfg.ArchiveDir = filepath.Join(defaultArchiveDir, subDir)
 	}
-
-	zl, err := nglog.New(
-		defaultLogType,
-		[]nglog.Option{
-			nglog.WithWriter(r),
-			nglog.WithEncoder(nglog.ConsoleEncoder),
-			nglog.WithEncoderCfg(nglog.EncoderConfig{MessageKey: "msg"}),
-			nglog.WithLevelEnabler(defaultLogLevel),
-			nglog.AddCaller(),
-			nglog.AddCallerSkip(defaultLogCallerSkip),
-		}...,
-	)
-	return typ, zl, err
+	return cfg
 }
 
 func defaultZapFields() map[string]interface{} {
@@ -374,7 +132,9 @@ func defaultZapEncoderCfg() nglog.EncoderConfig {
 		LevelKey:      "level",
 		EncodeLevel:   nglog.CapitalLevelEncoder,
 		TimeKey:       "@timestamp",
-		EncodeTime:    nglog.ChinaMilliTimeEncoder,
+		EncodeTime:    defaultTimeEncoder,
+		TimeLayout:    defaultTimeLayout,
+		TimeLocation:  defaultTimeLocation,
 		CallerKey:     "caller",
 		EncodeCaller:  nglog.JavaCallerEncoder,
 		StacktraceKey: "detail",
@@ -387,7 +147,9 @@ func defaultZapLoggerEncoderCfg() nglog.EncoderConfig {
 		LevelKey:      "level",
 		EncodeLevel:   nglog.CapitalLevelEncoder,
 		TimeKey:       "@timestamp",
-		EncodeTime:    nglog.ChinaMilliTimeEncoder,
+		EncodeTime:    defaultTimeEncoder,
+		TimeLayout:    defaultTimeLayout,
+		TimeLocation:  defaultTimeLocation,
 		CallerKey:     "caller",
 		EncodeCaller:  nglog.FullCallerEncoder,
 		StacktraceKey: "detail",
@@ -0,0 +1,510 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diycoder/elf/config"
+	"github.com/diycoder/elf/config/source/file"
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/kit/log/writer/async"
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Pipeline declares the loggers built by the log plugin and the routes
+// selecting them. A route maps a level name or the value of the "type" field
+// to the name of a logger, several routes may share one logger.
+//
+//	loggers:
+//	  - name: app
+//	    sub_dir: info/
+//	  - name: audit
+//	    sub_dir: audit/
+//	    filename: audit.log
+//	    encoder: json
+//	routes:
+//	  debug: app
+//	  info: app
+//	  audit: audit
+type Pipeline struct {
+	Loggers []LoggerConfig    `json:"loggers"`
+	Routes  map[string]string `json:"routes"`
+	// Redact masks sensitive data, nil masks the default keys
+	Redact *Redact `json:"redact"`
+}
+
+// LoggerConfig declares a named logger and its writer.
+type LoggerConfig struct {
+	Name string `json:"name"`
+	// Output is "terminal", "file", "both" or "sink", empty follows log_mod
+	// unless Sink is set
+	Output string `json:"output"`
+	// Sink is the url the sink output ships to, default log_sink, e.g.
+	// syslog://host:514, tcp://host:5170 or https://host/logs
+	Sink string `json:"sink"`
+	// SubDir is the directory under the log dir, e.g. "info/"
+	SubDir string `json:"sub_dir"`
+	// Filename defaults to the project log filename
+	Filename string `json:"filename"`
+	// Encoder is "console" (default) or "json"
+	Encoder string `json:"encoder"`
+	// TimeEncoder is "rfc3339", "rfc3339milli", "rfc3339nano", "china_milli"
+	// or "layout", default log_time_encoder
+	TimeEncoder string `json:"time_encoder"`
+	// TimeLayout is the layout of the "layout" time encoder, setting it
+	// alone selects that encoder
+	TimeLayout string `json:"time_layout"`
+	// MessageOnly writes the message without level, time and caller
+	MessageOnly bool `json:"message_only"`
+	// Level and MaxLevel restrict the logger to a level range
+	Level    string `json:"level"`
+	MaxLevel string `json:"max_level"`
+	// Stacktrace is the lowest level recording a stack trace, default error
+	Stacktrace string `json:"stacktrace"`
+	// Fields are added to every entry, next to host and project unless
+	// NoDefaultFields is set
+	Fields          map[string]interface{} `json:"fields"`
+	NoDefaultFields bool                   `json:"no_default_fields"`
+	// Sampling caps the entries written per message and level
+	Sampling *Sampling `json:"sampling"`
+	// Async writes in the background, loggers sharing a file share the
+	// settings of the first one. Sinks default to drop_newest
+	Async *Async `json:"async"`
+}
+
+// Async writes through a bounded queue, Policy is "block" (default),
+// "drop_newest" or "drop_oldest".
+type Async struct {
+	Size          int    `json:"size"`
+	Policy        string `json:"policy"`
+	FlushInterval string `json:"flush_interval"`
+}
+
+func (a *Async) options() ([]async.Option, error) {
+	policy, err := async.ParsePolicy(a.Policy)
+	if err != nil {
+		return nil, err
+	}
+	opts := []async.Option{async.WithPolicy(policy)}
+	if a.Size > 0 {
+		opts = append(opts, async.WithSize(a.Size))
+	}
+	if a.FlushInterval != "" {
+		d, err := time.ParseDuration(a.FlushInterval)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, async.WithFlushInterval(d))
+	}
+	return opts, nil
+}
+
+// Sampling writes the first Initial entries of a message and level within
+// every Tick, then every Thereafter-th one.
+type Sampling struct {
+	Tick       string `json:"tick"`
+	Initial    int    `json:"initial"`
+	Thereafter int    `json:"thereafter"`
+}
+
+func (s *Sampling) config() (nglog.SamplingConfig, error) {
+	cfg := nglog.SamplingConfig{Initial: s.Initial, Thereafter: s.Thereafter}
+	if s.Initial < 0 || s.Thereafter < 0 {
+		return cfg, fmt.Errorf("invalid sampling, initial and thereafter cannot be negative")
+	}
+	if s.Tick != "" {
+		d, err := time.ParseDuration(s.Tick)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Tick = d
+	}
+	return cfg, nil
+}
+
+var (
+	pipelineMu     sync.RWMutex
+	activePipeline *Pipeline
+)
+
+// DefaultPipeline returns the pipeline used when none is configured, it
+// writes debug, info, warn and error to their own directories and access,
+// tracing and micro to their own files.
+func DefaultPipeline() *Pipeline {
+	return &Pipeline{
+		Loggers: []LoggerConfig{
+			{Name: "debug", SubDir: "debug/"},
+			{Name: "info", SubDir: "info/"},
+			{Name: "warn", SubDir: "warn/"},
+			{Name: "error", SubDir: "error/", Stacktrace: "debug"},
+			{Name: "access", SubDir: "info/", Filename: "gw-access.log"},
+			{Name: "tracing", SubDir: "info/", Filename: "tracing.log", MessageOnly: true, NoDefaultFields: true},
+			{Name: "micro", SubDir: "info/", Filename: "micro.log"},
+		},
+		Routes: map[string]string{
+			"debug":   "debug",
+			"trace":   "debug",
+			"info":    "info",
+			"warn":    "warn",
+			"error":   "error",
+			"panic":   "error",
+			"fatal":   "error",
+			"access":  "access",
+			"tracing": "tracing",
+			"micro":   "micro",
+		},
+	}
+}
+
+// SetPipeline replaces the pipeline used by Init, it takes effect on the
+// next Init.
+func SetPipeline(p *Pipeline) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	pipelineMu.Lock()
+	defer pipelineMu.Unlock()
+	activePipeline = p
+	return nil
+}
+
+// GetPipeline returns the pipeline used by Init.
+func GetPipeline() *Pipeline {
+	pipelineMu.RLock()
+	defer pipelineMu.RUnlock()
+	if activePipeline == nil {
+		return DefaultPipeline()
+	}
+	return activePipeline
+}
+
+// LoadPipeline reads a pipeline at path of c, e.g. a config loaded from a
+// file, apollo or nacos. An empty path reads the whole config.
+func LoadPipeline(c config.Config, path ...string) (*Pipeline, error) {
+	p := new(Pipeline)
+	if err := c.Get(path...).Scan(p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadPipelineFile reads a pipeline from a json/yaml/toml file.
+func LoadPipelineFile(path string) (*Pipeline, error) {
+	c, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if err := c.Load(file.NewSource(file.WithPath(path))); err != nil {
+		return nil, err
+	}
+	return LoadPipeline(c)
+}
+
+// Validate checks the loggers are well formed and every route points to one.
+func (p *Pipeline) Validate() error {
+	if p == nil {
+		return fmt.Errorf("log pipeline is nil")
+	}
+	if len(p.Loggers) == 0 {
+		return fmt.Errorf("log pipeline has no loggers")
+	}
+
+	names := make(map[string]bool, len(p.Loggers))
+	for _, lc := range p.Loggers {
+		if lc.Name == "" {
+			return fmt.Errorf("log pipeline logger name cannot be empty")
+		}
+		if names[lc.Name] {
+			return fmt.Errorf("log pipeline logger %s declared twice", lc.Name)
+		}
+		names[lc.Name] = true
+
+		if _, err := parseOutput(lc.Output); err != nil {
+			return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+		}
+		if _, err := parseEncoder(lc.Encoder); err != nil {
+			return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+		}
+		if lc.TimeEncoder != "" {
+			if _, err := nglog.ParseTimeEncoder(lc.TimeEncoder); err != nil {
+				return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+			}
+		}
+		if lc.Async != nil {
+			if _, err := lc.Async.options(); err != nil {
+				return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+			}
+		}
+		if lc.Sampling != nil {
+			if _, err := lc.Sampling.config(); err != nil {
+				return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+			}
+		}
+		for _, lv := range []string{lc.Level, lc.MaxLevel, lc.Stacktrace} {
+			if lv == "" {
+				continue
+			}
+			if _, err := nglog.ParseLevel(lv); err != nil {
+				return fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+			}
+		}
+	}
+
+	if _, err := p.redact().redactor(); err != nil {
+		return fmt.Errorf("log pipeline redact: %v", err)
+	}
+
+	for typ, name := range p.Routes {
+		if !names[name] {
+			return fmt.Errorf("log pipeline route %s points to unknown logger %s", typ, name)
+		}
+	}
+
+	// panic and fatal are written at error level
+	shared := ""
+	for _, key := range []string{"error", "panic", "fatal"} {
+		name, ok := p.Routes[key]
+		if !ok {
+			continue
+		}
+		if shared != "" && name != shared {
+			return fmt.Errorf("log pipeline routes error, panic and fatal must point to the same logger")
+		}
+		shared = name
+	}
+	return nil
+}
+
+// build creates a logger writing to a tee of one core per logger, the cores
+// select the entries by the routes. Loggers writing to the same file share
+// one writer. The async writers are returned to be closed on exit.
+func (p *Pipeline) build() (*pLogger, []io.Closer, error) {
+	redactor, err := p.redact().redactor()
+	if err != nil {
+		return nil, nil, err
+	}
+	b := &pipelineBuilder{writers: make(map[string]io.Writer), redactor: redactor}
+	table := newRouteTable()
+	cores := make(map[string]*routeCore, len(p.Loggers))
+	for _, lc := range p.Loggers {
+		core, err := b.core(lc)
+		if err != nil {
+			closeAll(b.closers)
+			return nil, nil, fmt.Errorf("log pipeline logger %s: %v", lc.Name, err)
+		}
+		stack := zapcore.ErrorLevel
+		if lc.Stacktrace != "" {
+			lv, _ := nglog.ParseLevel(lc.Stacktrace)
+			stack = levelRoutes[lv.String()]
+		}
+		cores[lc.Name] = table.add(core, stack)
+	}
+	for key, name := range p.Routes {
+		table.route(cores[name], key)
+	}
+
+	// entries no route selects are written to the terminal
+	fallback, err := nglog.NewCore(nglog.WithWriter(io.MultiWriter(os.Stdout)), nglog.WithRedactor(redactor))
+	if err != nil {
+		closeAll(b.closers)
+		return nil, nil, err
+	}
+	table.addFallback(fallback)
+
+	core := table.tee()
+	if hs := hooks(); len(hs) > 0 {
+		core = zapcore.NewTee(core, nglog.NewHookCore(redactor, hs...))
+	}
+	zl, err := nglog.NewZapLogger(
+		nglog.WithZapCore(core),
+		nglog.WithZapOptions([]zap.Option{
+			zap.AddCaller(),
+			zap.AddCallerSkip(defaultLogCallerSkip),
+			zap.AddStacktrace(table.stackEnabler()),
+		}),
+	)
+	if err != nil {
+		closeAll(b.closers)
+		return nil, nil, err
+	}
+	pl := newRoutePLogger(zl, table)
+	pl.files = b.files
+	return pl, b.closers, nil
+}
+
+type pipelineBuilder struct {
+	writers  map[string]io.Writer
+	closers  []io.Closer
+	files    []*rotatelogs.RotateLogs
+	redactor *nglog.Redactor
+}
+
+func (p *Pipeline) redact() *Redact {
+	if p.Redact == nil {
+		return defaultRedact
+	}
+	return p.Redact
+}
+
+func (b *pipelineBuilder) writer(lc LoggerConfig, mod int, filename string) (io.Writer, error) {
+	sink := lc.Sink
+	if sink == "" {
+		sink = defaultLogSink
+	}
+	key := fmt.Sprint(mod, ":", lc.SubDir, filename)
+	if mod == outSink {
+		key = fmt.Sprint(mod, ":", sink)
+	}
+	if w, ok := b.writers[key]; ok {
+		return w, nil
+	}
+
+	w, file, err := newWriter(mod, lc.SubDir, filename, sink)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		b.files = append(b.files, file)
+	}
+	cfg := lc.Async
+	if cfg == nil {
+		cfg = defaultAsync
+	}
+	if cfg == nil && mod == outSink {
+		cfg = defaultSinkAsync
+	}
+	if cfg != nil {
+		opts, err := cfg.options()
+		if err != nil {
+			return nil, err
+		}
+		aw := async.NewWriter(w, opts...)
+		b.closers = append(b.closers, aw)
+		w = aw
+	}
+	b.writers[key] = w
+	return w, nil
+}
+
+func (b *pipelineBuilder) core(lc LoggerConfig) (zapcore.Core, error) {
+	mod, err := parseOutput(lc.Output)
+	if err != nil {
+		return nil, err
+	}
+	if lc.Output == "" && lc.Sink != "" {
+		mod = outSink
+	}
+	filename := lc.Filename
+	if filename == "" {
+		filename = getDefaultLogFilename()
+	}
+
+	w, err := b.writer(lc, mod, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, _ := parseEncoder(lc.Encoder)
+	opts := []nglog.Option{
+		nglog.WithWriter(w),
+		nglog.WithEncoder(encoder),
+		nglog.WithLevelEnabler(defaultLogLevel),
+		nglog.WithRedactor(b.redactor),
+	}
+	if lc.MessageOnly {
+		opts = append(opts, nglog.WithEncoderCfg(nglog.EncoderConfig{MessageKey: "msg"}))
+	} else {
+		opts = append(opts, nglog.WithEncoderCfg(lc.encoderConfig()))
+	}
+
+	fields := make(map[string]interface{})
+	if !lc.NoDefaultFields {
+		fields = defaultZapFields()
+	}
+	for k, v := range lc.Fields {
+		fields[k] = v
+	}
+	if len(fields) > 0 {
+		opts = append(opts, nglog.Fields(fields))
+	}
+
+	if lc.Level != "" {
+		lv, _ := nglog.ParseLevel(lc.Level)
+		opts = append(opts, nglog.WithLevelEnabler(lv))
+	}
+	if lc.MaxLevel != "" {
+		lv, _ := nglog.ParseLevel(lc.MaxLevel)
+		opts = append(opts, nglog.WithMaxLevel(lv))
+	}
+	if lc.Sampling != nil {
+		cfg, err := lc.Sampling.config()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nglog.WithSampling(cfg))
+	}
+
+	return nglog.NewCore(opts...)
+}
+
+// encoderConfig is the default encoder config with the time settings of lc.
+func (lc LoggerConfig) encoderConfig() nglog.EncoderConfig {
+	cfg := defaultZapEncoderCfg()
+	if lc.TimeLayout != "" {
+		cfg.EncodeTime = nglog.LayoutTimeEncoder
+		cfg.TimeLayout = lc.TimeLayout
+	}
+	if lc.TimeEncoder != "" {
+		cfg.EncodeTime, _ = nglog.ParseTimeEncoder(lc.TimeEncoder)
+	}
+	return cfg
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func parseOutput(output string) (int, error) {
+	switch strings.ToLower(output) {
+	case "":
+		return defaultLogMod, nil
+	case "terminal", "0":
+		return outTerminal, nil
+	case "file", "1":
+		return outFile, nil
+	case "both", "2":
+		return outTerminalAndFile, nil
+	case "sink", "3":
+		return outSink, nil
+	}
+	return 0, fmt.Errorf("invalid output %q", output)
+}
+
+func parseEncoder(encoder string) (nglog.Encoder, error) {
+	switch strings.ToLower(encoder) {
+	case "", "console":
+		return nglog.ConsoleEncoder, nil
+	case "json":
+		return nglog.JSONEncoder, nil
+	}
+	return 0, fmt.Errorf("invalid encoder %q", encoder)
+}
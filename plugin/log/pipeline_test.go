@@ -0,0 +1,406 @@
+package log
+
+import (
+	"context"
+	stdlog "log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/kit/log/alert"
+)
+
+const testPipeline = `
+loggers:
+  - name: app
+    output: file
+    sub_dir: app/
+    filename: app.log
+    max_level: info
+  - name: audit
+    output: file
+    sub_dir: audit/
+    filename: audit.log
+    encoder: json
+    fields:
+      stream: audit
+routes:
+  debug: app
+  info: app
+  audit: audit
+`
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "pipeline.yaml")
+	if err := os.WriteFile(conf, []byte(testPipeline), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPipelineFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl.Debug("debug message")
+	pl.Info("info message")
+	pl.WithField("type", "audit").Info("user login")
+
+	app := readLog(t, filepath.Join(dir, "app", "app.log"))
+	if !strings.Contains(app, "debug message") || !strings.Contains(app, "info message") {
+		t.Fatalf("debug and info should be merged, got %q", app)
+	}
+	audit := readLog(t, filepath.Join(dir, "audit", "audit.log"))
+	if !strings.Contains(audit, `"msg":"user login"`) || !strings.Contains(audit, `"stream":"audit"`) {
+		t.Fatalf("unexpected audit log %q", audit)
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	if err := DefaultPipeline().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []*Pipeline{
+		nil,
+		{},
+		{Loggers: []LoggerConfig{{Name: "a"}, {Name: "a"}}},
+		{Loggers: []LoggerConfig{{Name: "a", Encoder: "xml"}}},
+		{Loggers: []LoggerConfig{{Name: "a", TimeEncoder: "epoch"}}},
+		{Loggers: []LoggerConfig{{Name: "a", Level: "verbose"}}},
+		{Loggers: []LoggerConfig{{Name: "a"}}, Routes: map[string]string{"info": "b"}},
+	}
+	for i, p := range tests {
+		if err := p.Validate(); err == nil {
+			t.Errorf("pipeline %d should be invalid", i)
+		}
+	}
+}
+
+func TestPipelineTime(t *testing.T) {
+	dir := t.TempDir()
+	logDir, loc, timeLoc, pattern := defaultLogDir, defaultLocation, defaultTimeLocation, defaultRotatePattern
+	defer func() {
+		defaultLogDir, defaultLocation, defaultTimeLocation, defaultRotatePattern = logDir, loc, timeLoc, pattern
+	}()
+	defaultLogDir = dir + "/"
+	var err error
+	if defaultLocation, err = parseLocation("UTC"); err != nil {
+		t.Fatal(err)
+	}
+	defaultTimeLocation = defaultLocation
+	defaultRotatePattern = ".%Y%m%d"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{
+			Name:       "app",
+			Output:     "file",
+			SubDir:     "app/",
+			Filename:   "app.log",
+			Encoder:    "json",
+			TimeLayout: "2006-01-02T15:04:05Z07:00",
+		}},
+		Routes: map[string]string{"info": "app"},
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl.Info("utc message")
+
+	fn := filepath.Join(dir, "app", "app.log."+time.Now().UTC().Format("20060102"))
+	if got := readLog(t, fn); !strings.Contains(got, `"@timestamp":"`+time.Now().UTC().Format("2006-01-02T")) ||
+		!strings.Contains(got, `Z","`) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func readLog(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPipelineAsync(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{
+			Name:     "app",
+			Output:   "file",
+			Filename: "app.log",
+			Async:    &Async{Size: 16, Policy: "drop_oldest", FlushInterval: "1h"},
+		}},
+		Routes: map[string]string{"info": "app"},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	pl, closers, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl.Info("buffered")
+	if err := pl.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if app := readLog(t, filepath.Join(dir, "app.log")); !strings.Contains(app, "buffered") {
+		t.Fatalf("entry should be flushed by Sync, got %q", app)
+	}
+
+	pl.Info("last line")
+	if err := closeAll(closers); err != nil {
+		t.Fatal(err)
+	}
+	if app := readLog(t, filepath.Join(dir, "app.log")); !strings.Contains(app, "last line") {
+		t.Fatalf("entry should be flushed by Close, got %q", app)
+	}
+}
+
+func TestInitReplace(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	old, oldSlog := defaultLog, slog.Default()
+	defer func() {
+		defaultLogDir = logDir
+		SetLogger(old)
+		slog.SetDefault(oldSlog)
+		stdlog.SetOutput(os.Stderr)
+		pipelineMu.Lock()
+		activePipeline = nil
+		pipelineMu.Unlock()
+	}()
+	defaultLogDir = dir + "/"
+
+	if err := SetPipeline(&Pipeline{
+		Loggers: []LoggerConfig{{
+			Name:     "app",
+			Output:   "file",
+			Filename: "app.log",
+			Async:    &Async{FlushInterval: "1h"},
+		}},
+		Routes: map[string]string{"info": "app"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	derived := WithField("svc", "user")
+	derived.Info("before")
+	if err := Init(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if app := readLog(t, filepath.Join(dir, "app.log")); !strings.Contains(app, "before") {
+		t.Fatalf("replaced logger should be flushed, got %q", app)
+	}
+
+	// the writers of the replaced logger stay open until Close
+	derived.Info("after")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	if app := readLog(t, filepath.Join(dir, "app.log")); !strings.Contains(app, "after") {
+		t.Fatalf("derived logger should still write, got %q", app)
+	}
+}
+
+func TestPipelineRoute(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{
+			{Name: "info", Output: "file", Filename: "info.log", Encoder: "json", NoDefaultFields: true},
+			{Name: "error", Output: "file", Filename: "error.log", Encoder: "json", NoDefaultFields: true},
+			{Name: "access", Output: "file", Filename: "access.log", Encoder: "json", NoDefaultFields: true},
+			{Name: "tracing", Output: "file", Filename: "tracing.log", MessageOnly: true, NoDefaultFields: true},
+		},
+		Routes: map[string]string{
+			"info":    "info",
+			"error":   "error",
+			"panic":   "error",
+			"access":  "access",
+			"tracing": "tracing",
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl.WithField("a", 1).WithField("a", 2).Info("info message")
+	pl.WithField("type", "unknown").Info("unrouted type")
+	pl.Panic("panic message")
+	pl.WithField("type", "access").Warn("access message")
+	pl.WithField("type", "tracing").WithField("span", "x").Info("span")
+
+	info := readLog(t, filepath.Join(dir, "info.log"))
+	if strings.Count(info, `"a":`) != 1 || !strings.Contains(info, `"a":2`) {
+		t.Fatalf("a replaced field should be written once, got %q", info)
+	}
+	if !strings.Contains(info, "unrouted type") || !strings.Contains(info, "pipeline_test.go") {
+		t.Fatalf("unexpected info log %q", info)
+	}
+	errLog := readLog(t, filepath.Join(dir, "error.log"))
+	if !strings.Contains(errLog, "panic message") || !strings.Contains(errLog, `"detail":`) {
+		t.Fatalf("unexpected error log %q", errLog)
+	}
+	access := readLog(t, filepath.Join(dir, "access.log"))
+	if !strings.Contains(access, "access message") || !strings.Contains(access, `"type":"access"`) ||
+		strings.Contains(info+errLog, "access message") {
+		t.Fatalf("unexpected access log %q", access)
+	}
+	if tracing := readLog(t, filepath.Join(dir, "tracing.log")); tracing != "span\t{\"span\": \"x\"}\n" {
+		t.Fatalf("unexpected tracing log %q", tracing)
+	}
+}
+
+func TestPipelineRedact(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{Name: "app", Output: "file", Filename: "app.log", Encoder: "json"}},
+		Routes:  map[string]string{"info": "app"},
+		Redact: &Redact{
+			Values: []string{"mobile"},
+			Rules:  []RedactRule{{Name: "order", Pattern: `order-(\d+)`, Style: "partial", Tail: 2}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl.Infow("paid order-123456", "password", "p@ss", "mobile", "13812345678")
+
+	app := readLog(t, filepath.Join(dir, "app.log"))
+	for _, want := range []string{"order-****56", `"password":"******"`, `"mobile":"138****5678"`} {
+		if !strings.Contains(app, want) {
+			t.Errorf("want %q in %q", want, app)
+		}
+	}
+
+	p.Redact.Values = []string{"phone"}
+	if err := p.Validate(); err == nil {
+		t.Fatal("unknown value rule should be invalid")
+	}
+}
+
+func BenchmarkPLogger(b *testing.B) {
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = b.TempDir() + "/"
+
+	b.Run("selector", func(b *testing.B) {
+		w, _, err := newWriter(outFile, "selector/", "app.log", "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		zl, err := nglog.New(defaultLogType,
+			nglog.WithWriter(w),
+			nglog.WithEncoder(defaultLogEncoder),
+			nglog.WithEncoderCfg(defaultZapEncoderCfg()),
+			nglog.Fields(defaultZapFields()),
+			nglog.AddCaller(),
+			nglog.AddCallerSkip(defaultLogCallerSkip),
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pl := NewPLogger()
+		_ = pl.Register("info", zl)
+		benchmarkPLogger(b, pl)
+	})
+
+	b.Run("tee", func(b *testing.B) {
+		p := &Pipeline{
+			Loggers: []LoggerConfig{{Name: "app", Output: "file", SubDir: "tee/", Filename: "app.log"}},
+			Routes:  map[string]string{"info": "app"},
+		}
+		pl, _, err := p.build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkPLogger(b, pl)
+	})
+}
+
+func benchmarkPLogger(b *testing.B, pl *pLogger) {
+	l := pl.WithFields(map[string]interface{}{"user": "u1", "request_id": "r1", "attempt": 3})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled")
+	}
+}
+
+func TestPipelineAlert(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	var alerts []*alert.Alert
+	a, err := alert.New(alert.WithNotifier(alert.NotifierFunc(func(ctx context.Context, a *alert.Alert) error {
+		alerts = append(alerts, a)
+		return nil
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { defaultAlerter = nil }()
+	defaultAlerter = a
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{Name: "app", Output: "file", Filename: "app.log"}},
+		Routes:  map[string]string{"info": "app", "error": "app"},
+	}
+	pl, closers, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(closers)
+
+	pl.Info("not alerted")
+	pl.WithField("type", "audit").Errorw("login failed", "password", "secret")
+	a.Close()
+
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts", len(alerts))
+	}
+	if e := alerts[0]; e.Message != "login failed" || e.Fields["password"] != "******" ||
+		e.Fields["type"] != "audit" || !strings.Contains(e.Caller, "pipeline_test.go") {
+		t.Fatalf("alert %+v", e)
+	}
+}
@@ -1,18 +1,31 @@
 package log
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/diycoder/elf/kit/grpool"
+	nglog "github.com/diycoder/elf/kit/log"
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
 	"github.com/diycoder/elf/plugin"
 
 	"github.com/urfave/cli/v2"
 )
 
 type log struct {
-	md map[string]string
+	md          map[string]string
+	stopWatch   func() error
+	stopSignals func() error
+	stopDump    func() error
+	quota       io.Closer
 }
 
 const (
@@ -36,12 +49,180 @@ func (l *log) Flags() []cli.Flag {
 			Usage:   "Sets the time between rotation, e.g. \"24m\", \"24h\".",
 			EnvVars: []string{"LOG_DURATION"},
 		},
+		&cli.IntFlag{
+			Name:    "log_max_size",
+			Usage:   "Sets the size in MB a log file rolls over to name.1, name.2 within the rotation, 0 disables.",
+			EnvVars: []string{"LOG_MAX_SIZE"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_compress",
+			Usage:   "Gzip the rotated log files in the background.",
+			EnvVars: []string{"LOG_COMPRESS"},
+		},
+		&cli.StringFlag{
+			Name:    "log_archive_dir",
+			Usage:   "Move the rotated log files into the dir, on the same file system as log dir.",
+			EnvVars: []string{"LOG_ARCHIVE_DIR"},
+		},
+		&cli.IntFlag{
+			Name:    "log_quota",
+			Usage:   "Sets the total size in MB of the log dir, the oldest rotated files of all loggers are removed beyond it, 0 disables.",
+			EnvVars: []string{"LOG_QUOTA"},
+		},
+		&cli.IntFlag{
+			Name:    "log_min_free",
+			Usage:   "Sets the free space in MB of the log file system to keep by removing the oldest rotated files, 0 disables.",
+			EnvVars: []string{"LOG_MIN_FREE"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_signals",
+			Usage:   "Reopen the log files on SIGHUP, e.g. after logrotate moved them, and rotate them on SIGUSR1.",
+			EnvVars: []string{"LOG_SIGNALS"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_dump_signal",
+			Usage:   "Write a goroutine dump to the crash dir of the log dir on SIGQUIT instead of exiting.",
+			EnvVars: []string{"LOG_DUMP_SIGNAL"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_recover",
+			Value:   true,
+			Usage:   "Recover the panics of the http handlers, log them with the stack and reply 500.",
+			EnvVars: []string{"LOG_RECOVER"},
+		},
+		&cli.StringFlag{
+			Name:    "log_timezone",
+			Usage:   "Sets the time zone naming the log files and of the logged times, e.g. \"UTC\", \"Local\", \"Europe/Berlin\", default Asia/Shanghai for the files and local for the times.",
+			EnvVars: []string{"LOG_TIMEZONE"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_utc",
+			Usage:   "Use UTC for the log files and the logged times, it takes precedence over log_timezone.",
+			EnvVars: []string{"LOG_UTC"},
+		},
+		&cli.StringFlag{
+			Name:    "log_pattern",
+			Usage:   "Sets the strftime suffix of the rotated log files, e.g. \".%Y%m%d\", default \".%Y%m%d%H%M\".",
+			EnvVars: []string{"LOG_PATTERN"},
+		},
+		&cli.StringFlag{
+			Name:    "log_time_encoder",
+			Usage:   "Sets the time encoder, e.g. \"rfc3339\", \"rfc3339milli\", \"rfc3339nano\", \"china_milli\" (default), \"layout\".",
+			EnvVars: []string{"LOG_TIME_ENCODER"},
+		},
+		&cli.StringFlag{
+			Name:    "log_time_layout",
+			Usage:   "Sets the Go time layout of the logged times, it selects the layout time encoder unless log_time_encoder is set.",
+			EnvVars: []string{"LOG_TIME_LAYOUT"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_access",
+			Value:   true,
+			Usage:   "Write an access line per http request with the access type.",
+			EnvVars: []string{"LOG_ACCESS"},
+		},
+		&cli.StringFlag{
+			Name:    "log_access_exclude",
+			Usage:   "Set the paths without access lines, e.g. \"/health/**,/metrics\".",
+			EnvVars: []string{"LOG_ACCESS_EXCLUDE"},
+		},
+		&cli.StringFlag{
+			Name:    "log_access_trusted_proxies",
+			Value:   defaultTrustedProxies,
+			Usage:   "Set the addresses or CIDRs of the proxies whose X-Forwarded-For and X-Real-Ip are trusted, empty trusts none.",
+			EnvVars: []string{"LOG_ACCESS_TRUSTED_PROXIES"},
+		},
+		&cli.StringFlag{
+			Name:    "log_access_slow",
+			Usage:   "Sets the latency from which the access lines are written at warn level, e.g. \"1s\".",
+			EnvVars: []string{"LOG_ACCESS_SLOW"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_access_body",
+			Usage:   "Write the request and response bodies in the access lines.",
+			EnvVars: []string{"LOG_ACCESS_BODY"},
+		},
+		&cli.IntFlag{
+			Name:    "log_access_body_limit",
+			Value:   defaultAccessBodyLimit,
+			Usage:   "Sets the bytes of a body written in the access lines.",
+			EnvVars: []string{"LOG_ACCESS_BODY_LIMIT"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_target",
+			Usage:   "Set the url the error logs are alerted to, or the command run per alert with the command type.",
+			EnvVars: []string{"LOG_ALERT_TARGET"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_type",
+			Value:   "webhook",
+			Usage:   "Sets the alert target type, e.g. \"webhook\", \"dingtalk\", \"feishu\", \"slack\", \"command\".",
+			EnvVars: []string{"LOG_ALERT_TYPE"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_secret",
+			Usage:   "Set the secret signing the dingtalk and feishu robot requests.",
+			EnvVars: []string{"LOG_ALERT_SECRET"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_body",
+			Usage:   "Set the text/template of the webhook body, e.g. '{\"text\": {{json .Message}}, \"count\": {{.Count}}}', default the alert as json.",
+			EnvVars: []string{"LOG_ALERT_BODY"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_level",
+			Value:   "error",
+			Usage:   "Sets the lowest level alerted.",
+			EnvVars: []string{"LOG_ALERT_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:    "log_alert_window",
+			Value:   "1m",
+			Usage:   "Sets the window the identical entries are alerted once within, with their count after it.",
+			EnvVars: []string{"LOG_ALERT_WINDOW"},
+		},
 		&cli.StringFlag{
 			Name:    "log_mod",
 			Value:   "0",
-			Usage:   "Sets the log mod, e.g. \"0\", \"1\", \"2\" .",
+			Usage:   "Sets the log mod, e.g. \"0\", \"1\", \"2\", \"3\" (sink) .",
 			EnvVars: []string{"LOG_MOD"},
 		},
+		&cli.StringFlag{
+			Name:    "log_sink",
+			Usage:   "Set the url the logs are shipped to in the sink mod, e.g. syslog://host:514, tcp://host:5170, https://host/logs.",
+			EnvVars: []string{"LOG_SINK"},
+		},
+		&cli.StringFlag{
+			Name:    "log_pipeline",
+			Usage:   "Set the json/yaml/toml file declaring the loggers and their routes.",
+			EnvVars: []string{"LOG_PIPELINE"},
+		},
+		&cli.BoolFlag{
+			Name:    "log_async",
+			Usage:   "Write the logs in the background, the pipeline async settings take precedence.",
+			EnvVars: []string{"LOG_ASYNC"},
+		},
+		&cli.StringFlag{
+			Name:    "log_redact_values",
+			Usage:   "Mask the values matching the builtin rules, e.g. mobile,id_card,bank_card,email.",
+			EnvVars: []string{"LOG_REDACT_VALUES"},
+		},
+		&cli.StringFlag{
+			Name:    "log_watch_file",
+			Usage:   "Set the json/yaml/toml file providing level, levels and fields, changes are applied at runtime.",
+			EnvVars: []string{"LOG_WATCH_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "log_watch_config",
+			Usage:   "Set the registered config providing the settings at log_watch_path, e.g. \"apollo\", \"nacos\".",
+			EnvVars: []string{"LOG_WATCH_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:    "log_watch_path",
+			Value:   "log",
+			Usage:   "Set the dot separated path of the settings in log_watch_config.",
+			EnvVars: []string{"LOG_WATCH_PATH"},
+		},
 	}
 }
 
@@ -54,10 +235,11 @@ func (l *log) Commands() []*cli.Command {
 // the existing handler so it can be wrapped to create a call chain.
 func (l *log) Handler() plugin.Handler {
 	return func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-			// serve the request
-			h.ServeHTTP(rw, r)
-		})
+		// serve the request and write the access line
+		if defaultRecover {
+			h = RecoverHandler(h)
+		}
+		return AccessHandler(defaultAccess, h)
 	}
 }
 
@@ -85,6 +267,9 @@ func (l *log) Init(ctx *cli.Context) error {
 	if mod, err := strconv.Atoi(logMod); err == nil && mod >= 0 {
 		defaultLogMod = mod
 	}
+	if sink := ctx.String("log_sink"); sink != "" {
+		defaultLogSink = sink
+	}
 
 	// 日志切割间隔
 	rotateDuration := ctx.String("log_duration")
@@ -96,7 +281,238 @@ func (l *log) Init(ctx *cli.Context) error {
 		return err
 	}
 
-	return Init(l.md)
+	// 时区、文件名格式及时间格式
+	if err := initTime(ctx); err != nil {
+		return err
+	}
+
+	// 访问日志
+	if err := initAccess(ctx); err != nil {
+		return err
+	}
+	defaultRecover = ctx.Bool("log_recover")
+
+	// 日志按大小切割
+	if size := ctx.Int("log_max_size"); size > 0 {
+		defaultMaxSize = int64(size) << 20
+	}
+
+	// 切割后压缩、归档
+	if ctx.Bool("log_compress") {
+		defaultCompressor = rotatelogs.Gzip
+	}
+	if dir := ctx.String("log_archive_dir"); dir != "" {
+		defaultArchiveDir = dir
+	}
+
+	// 异步写日志
+	if ctx.Bool("log_async") {
+		defaultAsync = &Async{}
+	}
+
+	if values := ctx.String("log_redact_values"); values != "" {
+		defaultRedact = &Redact{Values: strings.Split(values, ",")}
+	}
+
+	// 日志管道配置
+	if path := ctx.String("log_pipeline"); path != "" {
+		p, err := LoadPipelineFile(path)
+		if err != nil {
+			return err
+		}
+		if err := SetPipeline(p); err != nil {
+			return err
+		}
+	}
+
+	// 错误日志告警
+	if defaultAlerter != nil {
+		_ = defaultAlerter.Close()
+		defaultAlerter = nil
+	}
+	a, err := newAlerter(ctx)
+	if err != nil {
+		return err
+	}
+	defaultAlerter = a
+
+	if err := Init(l.md); err != nil {
+		return err
+	}
+
+	// grpool 任务的 panic 及堆栈写入日志
+	grpool.SetPanicHandler(logTaskPanic)
+
+	// 日志目录配额
+	if l.quota != nil {
+		_ = l.quota.Close()
+		l.quota = nil
+	}
+	maxBytes, minFree := int64(ctx.Int("log_quota"))<<20, int64(ctx.Int("log_min_free"))<<20
+	if defaultLogMod != outTerminal && defaultLogMod != outSink && (maxBytes > 0 || minFree > 0) {
+		q, err := newQuota(maxBytes, minFree)
+		if err != nil {
+			return err
+		}
+		l.quota = q
+	}
+
+	// 信号重新打开、切割日志文件
+	if ctx.Bool("log_signals") && l.stopSignals == nil {
+		stop, err := handleSignals()
+		if err != nil {
+			return err
+		}
+		l.stopSignals = stop
+	}
+
+	// SIGQUIT 输出 goroutine 堆栈
+	if ctx.Bool("log_dump_signal") && l.stopDump == nil {
+		stop, err := handleDumpSignal()
+		if err != nil {
+			return err
+		}
+		l.stopDump = stop
+	}
+
+	// 动态日志配置，文件优先于配置中心
+	if l.stopWatch != nil {
+		_ = l.stopWatch()
+		l.stopWatch = nil
+	}
+	if path := ctx.String("log_watch_file"); path != "" {
+		stop, err := WatchFile(path)
+		if err != nil {
+			return err
+		}
+		l.stopWatch = stop
+	} else if name := ctx.String("log_watch_config"); name != "" {
+		var path []string
+		if p := ctx.String("log_watch_path"); p != "" {
+			path = strings.Split(p, ".")
+		}
+		stop, err := WatchNamed(name, path...)
+		if err != nil {
+			return err
+		}
+		l.stopWatch = stop
+	}
+	return nil
+}
+
+func initAccess(ctx *cli.Context) error {
+	a := &Access{
+		Disable:   !ctx.Bool("log_access"),
+		Body:      ctx.Bool("log_access_body"),
+		BodyLimit: ctx.Int("log_access_body_limit"),
+	}
+	for _, pattern := range strings.Split(ctx.String("log_access_exclude"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			a.Exclude = append(a.Exclude, pattern)
+		}
+	}
+	proxies, err := parseTrustedProxies(ctx.String("log_access_trusted_proxies"))
+	if err != nil {
+		return fmt.Errorf("[log] invalid log_access_trusted_proxies: %v", err)
+	}
+	a.TrustedProxies = proxies
+	if slow := ctx.String("log_access_slow"); slow != "" {
+		d, err := time.ParseDuration(slow)
+		if err != nil {
+			return fmt.Errorf("[log] invalid log_access_slow %q", slow)
+		}
+		a.Slow = d
+	}
+	*defaultAccess = *a
+	return nil
+}
+
+func initTime(ctx *cli.Context) error {
+	if name := ctx.String("log_timezone"); name != "" {
+		loc, err := parseLocation(name)
+		if err != nil {
+			return fmt.Errorf("[log] invalid log_timezone %q: %v", name, err)
+		}
+		defaultLocation, defaultTimeLocation = loc, loc
+	}
+	if ctx.Bool("log_utc") {
+		defaultLocation, defaultTimeLocation = time.UTC, time.UTC
+	}
+	if pattern := ctx.String("log_pattern"); pattern != "" {
+		defaultRotatePattern = pattern
+	}
+	if layout := ctx.String("log_time_layout"); layout != "" {
+		defaultTimeEncoder, defaultTimeLayout = nglog.LayoutTimeEncoder, layout
+	}
+	if name := ctx.String("log_time_encoder"); name != "" {
+		enc, err := nglog.ParseTimeEncoder(name)
+		if err != nil {
+			return fmt.Errorf("[log] invalid log_time_encoder %q", name)
+		}
+		defaultTimeEncoder = enc
+	}
+	return nil
+}
+
+// Check checks the log directory is writable when logging to files.
+func (l *log) Check(ctx context.Context) error {
+	if defaultLogMod == outTerminal || defaultLogMod == outSink {
+		return nil
+	}
+	fh, err := ioutil.TempFile(defaultLogDir, ".health")
+	if err != nil {
+		return err
+	}
+	fh.Close()
+	return os.Remove(fh.Name())
+}
+
+// Actions can be run through the admin plugin.
+func (l *log) Actions() map[string]plugin.Action {
+	return map[string]plugin.Action{
+		"level": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			lv, err := nglog.ParseLevel(args["level"])
+			if err != nil {
+				return nil, err
+			}
+			if err := SetLogLevel(lv); err != nil {
+				return nil, err
+			}
+			return map[string]string{"level": lv.String()}, nil
+		},
+		"reopen": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			return nil, Reopen()
+		},
+		"rotate": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			return nil, Rotate()
+		},
+	}
+}
+
+// Close stops watching the settings, the quota and the signals, sends the pending alerts,
+// flushes the loggers and closes the async writers.
+func (l *log) Close(ctx context.Context) error {
+	if l.stopWatch != nil {
+		_ = l.stopWatch()
+		l.stopWatch = nil
+	}
+	if l.quota != nil {
+		_ = l.quota.Close()
+		l.quota = nil
+	}
+	if l.stopSignals != nil {
+		_ = l.stopSignals()
+		l.stopSignals = nil
+	}
+	if l.stopDump != nil {
+		_ = l.stopDump()
+		l.stopDump = nil
+	}
+	if defaultAlerter != nil {
+		_ = defaultAlerter.Close()
+		defaultAlerter = nil
+	}
+	return Close()
 }
 
 // Name of the plugin
@@ -104,6 +520,10 @@ func (l *log) String() string {
 	return "log_setting"
 }
 
+func init() {
+	plugin.RegisterFactory("log", NewPlugin)
+}
+
 func NewPlugin() plugin.Plugin {
 	return &log{
 		md: make(map[string]string),
@@ -1,31 +1,73 @@
 package log
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
 	nglog "github.com/diycoder/elf/kit/log"
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
+	"go.uber.org/zap/zapcore"
 )
 
 var (
-	nopLogger, _              = nglog.New(nglog.ZapLogger, nglog.WithWriter(ioutil.Discard))
-	stdLogger, _              = nglog.New(nglog.ZapLogger, nglog.WithWriter(os.Stdout))
-	_            nglog.Logger = (*pLogger)(nil)
+	nopLogger, _                    = nglog.New(nglog.ZapLogger, nglog.WithWriter(ioutil.Discard))
+	stdLogger, _                    = nglog.New(nglog.ZapLogger, nglog.WithWriter(os.Stdout))
+	_            nglog.Logger       = (*pLogger)(nil)
+	_            nglog.CallerLogger = (*pLogger)(nil)
 )
 
+const typeKey = "type"
+
 type pLogger struct {
-	levelEnabler nglog.LevelEnabler
-	fields       sync.Map
-	selector     sync.Map
+	typ      string
+	fields   []nglog.Field
+	selector *sync.Map
+	// settings are shared with the derived loggers, so changing them on
+	// the fly applies to every logger created by With
+	settings *atomic.Pointer[settings]
+	// flusher writes the summaries of the rate limits, shared as settings
+	flusher *dropFlusher
+
+	// base is set when the logger is built from a pipeline: a single zap
+	// logger over a tee of the route cores, zl is base with the fields
+	// added, so the fields are not copied per entry
+	base  nglog.Logger
+	zl    nglog.Logger
+	table *routeTable
+	// files are the rotating files of the pipeline
+	files []*rotatelogs.RotateLogs
+}
+
+// settings of a pLogger which can be changed at runtime.
+type settings struct {
+	level    nglog.Level
+	levels   map[string]nglog.Level
+	fields   []nglog.Field
+	limiters map[string]*limiter
 }
 
 func NewPLogger() *pLogger {
-	return &pLogger{
-		levelEnabler: nglog.DebugLevel,
+	pl := &pLogger{
+		selector: new(sync.Map),
+		settings: new(atomic.Pointer[settings]),
+		flusher:  new(dropFlusher),
 	}
+	pl.settings.Store(&settings{level: nglog.DebugLevel})
+	return pl
+}
+
+// newRoutePLogger creates a logger writing to the route cores of table
+// through base.
+func newRoutePLogger(base nglog.Logger, table *routeTable) *pLogger {
+	pl := NewPLogger()
+	pl.base, pl.zl, pl.table = base, base, table
+	return pl
 }
 
 func (pl *pLogger) Debug(args ...interface{}) {
@@ -77,63 +119,205 @@ func (pl *pLogger) Panicf(format string, args ...interface{}) {
 	pl.doSelect(nglog.PanicLevel).Panicf(format, args...)
 }
 
+// Fatal writes the entry at error level, then writes a crash report,
+// flushes and closes all the loggers and exits.
 func (pl *pLogger) Fatal(args ...interface{}) {
-	pl.doSelect(nglog.FatalLevel).Fatal(args...)
+	pl.fatal(fmt.Sprint(args...))
 }
 
 func (pl *pLogger) Fatalf(format string, args ...interface{}) {
-	pl.doSelect(nglog.FatalLevel).Fatalf(format, args...)
+	pl.fatal(fmt.Sprintf(format, args...))
+}
+
+// fatal writes msg without the exit of the selected logger, the crash report
+// is written first.
+func (pl *pLogger) fatal(msg string) {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	pl.LogCaller(pcs[0], nglog.FatalLevel, msg)
+	crash("fatal: " + msg)
+}
+
+func (pl *pLogger) DebugCtx(ctx context.Context, args ...interface{}) {
+	pl.doSelect(nglog.DebugLevel).DebugCtx(ctx, args...)
+}
+
+func (pl *pLogger) DebugfCtx(ctx context.Context, format string, args ...interface{}) {
+	pl.doSelect(nglog.DebugLevel).DebugfCtx(ctx, format, args...)
+}
+
+func (pl *pLogger) InfoCtx(ctx context.Context, args ...interface{}) {
+	pl.doSelect(nglog.InfoLevel).InfoCtx(ctx, args...)
+}
+
+func (pl *pLogger) InfofCtx(ctx context.Context, format string, args ...interface{}) {
+	pl.doSelect(nglog.InfoLevel).InfofCtx(ctx, format, args...)
+}
+
+func (pl *pLogger) WarnCtx(ctx context.Context, args ...interface{}) {
+	pl.doSelect(nglog.WarnLevel).WarnCtx(ctx, args...)
+}
+
+func (pl *pLogger) WarnfCtx(ctx context.Context, format string, args ...interface{}) {
+	pl.doSelect(nglog.WarnLevel).WarnfCtx(ctx, format, args...)
+}
+
+func (pl *pLogger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	pl.doSelect(nglog.ErrorLevel).ErrorCtx(ctx, args...)
+}
+
+func (pl *pLogger) ErrorfCtx(ctx context.Context, format string, args ...interface{}) {
+	pl.doSelect(nglog.ErrorLevel).ErrorfCtx(ctx, format, args...)
+}
+
+func (pl *pLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	pl.doSelect(nglog.DebugLevel).Debugw(msg, keysAndValues...)
+}
+
+func (pl *pLogger) Infow(msg string, keysAndValues ...interface{}) {
+	pl.doSelect(nglog.InfoLevel).Infow(msg, keysAndValues...)
+}
+
+func (pl *pLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	pl.doSelect(nglog.WarnLevel).Warnw(msg, keysAndValues...)
+}
+
+func (pl *pLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	pl.doSelect(nglog.ErrorLevel).Errorw(msg, keysAndValues...)
+}
+
+// LogCaller logs on behalf of the caller at pc, e.g. for nglog.SlogHandler.
+func (pl *pLogger) LogCaller(pc uintptr, lv nglog.Level, msg string, fields ...nglog.Field) {
+	nglog.LogCaller(pl.doSelect(lv), pc, lv, msg, fields...)
 }
 
 func (pl *pLogger) WithField(key string, value interface{}) nglog.Logger {
 	if key == "" {
 		return pl
 	}
-	clone := pl.clone()
-	clone.fields.Store(key, value)
-	return clone
+	return pl.with(nglog.Any(key, value))
 }
 
 func (pl *pLogger) WithFields(fields map[string]interface{}) nglog.Logger {
 	if len(fields) == 0 {
 		return pl
 	}
-	clone := pl.clone()
+	fs := make([]nglog.Field, 0, len(fields))
 	for k, v := range fields {
-		clone.fields.Store(k, v)
+		fs = append(fs, nglog.Any(k, v))
 	}
+	return pl.with(fs...)
+}
 
-	return clone
+func (pl *pLogger) With(fields ...nglog.Field) nglog.Logger {
+	if len(fields) == 0 {
+		return pl
+	}
+	return pl.with(fields...)
+}
+
+func (pl *pLogger) WithContext(ctx context.Context) nglog.Logger {
+	return pl.WithFields(nglog.ContextFields(ctx))
 }
 
+// doSelect picks the logger registered for the "type" field or the level,
+// the fields are handed over as is without being copied.
 func (pl *pLogger) doSelect(lv nglog.Level) nglog.Logger {
-	if !pl.levelEnabler.Enabled(lv) {
+	s := pl.settings.Load()
+	if !s.enabled(pl.typ, lv) {
 		return nopLogger
 	}
+	if pl.zl != nil {
+		return pl.route(s, lv)
+	}
 
-	fields := make(map[string]interface{})
-	pl.fields.Range(func(key, value interface{}) bool {
-		fields[key.(string)] = value
-		return true
-	})
-
-	if v, ok := pl.fields.Load("type"); ok {
-		if typ, ok := v.(string); ok {
-			if zl, ok := pl.selector.Load(typ); ok && zl != nil {
-				// 如果是 tracing 就删除掉
-				if typ == "tracing" {
-					delete(fields, "type")
-				}
-				return zl.(nglog.Logger).WithFields(fields)
+	fields := pl.fields
+	if len(s.fields) > 0 {
+		fields = make([]nglog.Field, 0, len(s.fields)+len(pl.fields))
+		for _, f := range s.fields {
+			// fields of the logger take precedence over the global ones
+			if !hasKey(pl.fields, f.Key) {
+				fields = append(fields, f)
 			}
 		}
+		fields = append(fields, pl.fields...)
+	}
+
+	key, zl := pl.lookup(lv)
+	if l, ok := s.limiters[key]; ok && !l.allow(zl, lv) {
+		return nopLogger
 	}
 
+	// 如果是 tracing 就删除掉
+	if key == "tracing" {
+		return zl.With(withoutKey(pl.fields, typeKey)...)
+	}
+	return zl.With(fields...)
+}
+
+// route returns the logger of a pipeline, the cores select the route by the
+// level and the "type" field.
+func (pl *pLogger) route(s *settings, lv nglog.Level) nglog.Logger {
+	if len(s.limiters) > 0 {
+		key := pl.table.routeKey(pl.typ, lv.String())
+		if l, ok := s.limiters[key]; ok && !l.allow(pl.zl, lv) {
+			return nopLogger
+		}
+	}
+
+	if len(s.fields) == 0 {
+		return pl.zl
+	}
+	global := make([]nglog.Field, 0, len(s.fields))
+	for _, f := range s.fields {
+		// fields of the logger take precedence over the global ones
+		if !hasKey(pl.fields, f.Key) {
+			global = append(global, f)
+		}
+	}
+	return pl.zl.With(global...)
+}
+
+// logDropped writes the summary of the entries dropped by the rate limit of
+// key at the level of the limited entry, so it goes to their route even when
+// the route is capped below warn.
+func logDropped(l nglog.Logger, lv nglog.Level, key string, dropped uint64) {
+	msg := fmt.Sprintf("%d messages of %s dropped by rate limit", dropped, key)
+	switch {
+	case lv < nglog.InfoLevel:
+		l.Debug(msg)
+	case lv < nglog.WarnLevel:
+		l.Info(msg)
+	case lv < nglog.ErrorLevel:
+		l.Warn(msg)
+	default:
+		l.Error(msg)
+	}
+}
+
+// lookup returns the logger registered for the "type" field or the level,
+// and the key it is registered with.
+func (pl *pLogger) lookup(lv nglog.Level) (string, nglog.Logger) {
+	if pl.typ != "" {
+		if zl, ok := pl.selector.Load(pl.typ); ok && zl != nil {
+			return pl.typ, zl.(nglog.Logger)
+		}
+	}
 	if zl, ok := pl.selector.Load(lv.String()); ok && zl != nil {
-		return zl.(nglog.Logger).WithFields(fields)
+		return lv.String(), zl.(nglog.Logger)
 	}
+	return "", stdLogger
+}
 
-	return stdLogger.WithFields(fields)
+// enabled reports whether lv is logged, a level set for the type takes
+// precedence over the global one.
+func (s *settings) enabled(typ string, lv nglog.Level) bool {
+	if typ != "" {
+		if min, ok := s.levels[typ]; ok {
+			return min.Enabled(lv)
+		}
+	}
+	return s.level.Enabled(lv)
 }
 
 func (pl *pLogger) Select(lv nglog.Level) nglog.Logger {
@@ -149,6 +333,10 @@ func (pl *pLogger) Register(typ string, l nglog.Logger) error {
 		return errors.New("Logger cannot be nil ")
 	}
 
+	if pl.base != nil {
+		return errors.New("Logger is built from a pipeline, add a route instead ")
+	}
+
 	if _, ok := pl.selector.LoadOrStore(typ, l); ok {
 		return fmt.Errorf("Logger with type %s already registered ", typ)
 	}
@@ -158,29 +346,177 @@ func (pl *pLogger) Register(typ string, l nglog.Logger) error {
 
 // Changing level on the fly without app restart
 func (pl *pLogger) SetLogLevel(lv nglog.Level) error {
-	lvs := nglog.AllLevels()
-	for _, level := range lvs {
+	if !validLevel(lv) {
+		return fmt.Errorf("Invalid level: %v ", lv)
+	}
+	pl.update(func(s *settings) {
+		s.level = lv
+	})
+	return nil
+}
+
+// SetTypeLevels sets the levels of the types (or route keys) on the fly,
+// types not in levels follow the global level.
+func (pl *pLogger) SetTypeLevels(levels map[string]nglog.Level) error {
+	lvs := make(map[string]nglog.Level, len(levels))
+	for typ, lv := range levels {
+		if !validLevel(lv) {
+			return fmt.Errorf("Invalid level: %v ", lv)
+		}
+		lvs[typ] = lv
+	}
+	pl.update(func(s *settings) {
+		s.levels = lvs
+	})
+	return nil
+}
+
+// SetGlobalFields sets the fields added to every entry on the fly.
+func (pl *pLogger) SetGlobalFields(fields ...nglog.Field) {
+	fs := append([]nglog.Field(nil), fields...)
+	pl.update(func(s *settings) {
+		s.fields = fs
+	})
+}
+
+// SetRateLimits limits the entries of the types (or route keys) on the fly,
+// the dropped entries are summarized periodically.
+func (pl *pLogger) SetRateLimits(limits map[string]RateLimit) error {
+	limiters, err := newLimiters(limits)
+	if err != nil {
+		return err
+	}
+	var old map[string]*limiter
+	pl.update(func(s *settings) {
+		old, s.limiters = s.limiters, limiters
+	})
+	pl.replaceLimiters(old, limiters)
+	return nil
+}
+
+// replaceLimiters flushes the summaries of the replaced limiters, so their
+// dropped entries are not lost, and starts the summary ticker.
+func (pl *pLogger) replaceLimiters(old, limiters map[string]*limiter) {
+	flushLimiters(old)
+	if len(limiters) > 0 {
+		pl.flusher.start(pl.flushDropped)
+	}
+}
+
+func (pl *pLogger) flushDropped() {
+	flushLimiters(pl.settings.Load().limiters)
+}
+
+// Close stops the summary ticker of the rate limits and writes the summaries
+// of the entries dropped since the last one.
+func (pl *pLogger) Close() error {
+	pl.flusher.close()
+	pl.flushDropped()
+	return nil
+}
+
+func (pl *pLogger) update(fn func(s *settings)) {
+	for {
+		old := pl.settings.Load()
+		s := *old
+		fn(&s)
+		if pl.settings.CompareAndSwap(old, &s) {
+			return
+		}
+	}
+}
+
+func validLevel(lv nglog.Level) bool {
+	for _, level := range nglog.AllLevels() {
 		if level == lv {
-			pl.levelEnabler = lv
-			return nil
+			return true
 		}
 	}
-	return fmt.Errorf("Invalid level: %v ", lv)
+	return false
 }
 
-func (pl *pLogger) clone() *pLogger {
-	clone := &pLogger{
-		levelEnabler: pl.levelEnabler,
+// Sync flushes every registered logger once.
+func (pl *pLogger) Sync() error {
+	if pl.base != nil {
+		return pl.base.Sync()
 	}
 
-	pl.fields.Range(func(key, value interface{}) bool {
-		clone.fields.Store(key, value)
-		return true
-	})
-	pl.selector.Range(func(key, value interface{}) bool {
-		clone.selector.Store(key, value)
+	var errs []error
+	seen := make(map[nglog.Logger]bool)
+	pl.selector.Range(func(_, v interface{}) bool {
+		zl := v.(nglog.Logger)
+		if seen[zl] {
+			return true
+		}
+		seen[zl] = true
+		if err := zl.Sync(); err != nil {
+			errs = append(errs, err)
+		}
 		return true
 	})
+	return errors.Join(errs...)
+}
 
+// with returns a copy of the logger with fields added, a field replaces the
+// existing one with the same key.
+func (pl *pLogger) with(fields ...nglog.Field) *pLogger {
+	clone := &pLogger{
+		typ:      pl.typ,
+		selector: pl.selector,
+		settings: pl.settings,
+		flusher:  pl.flusher,
+		base:     pl.base,
+		table:    pl.table,
+		fields:   make([]nglog.Field, len(pl.fields), len(pl.fields)+len(fields)),
+	}
+	copy(clone.fields, pl.fields)
+
+	replacedAny := false
+	for _, f := range fields {
+		if f.Key == typeKey && f.Type == zapcore.StringType {
+			clone.typ = f.String
+		}
+		replaced := false
+		for i := range clone.fields {
+			if clone.fields[i].Key == f.Key {
+				clone.fields[i] = f
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			clone.fields = append(clone.fields, f)
+		}
+		replacedAny = replacedAny || replaced
+	}
+
+	// the fields are added to the cores once, a replaced field needs the
+	// cores to be derived from base again
+	if pl.base != nil {
+		if replacedAny {
+			clone.zl = pl.base.With(clone.fields...)
+		} else {
+			clone.zl = pl.zl.With(fields...)
+		}
+	}
 	return clone
 }
+
+func hasKey(fields []nglog.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutKey(fields []nglog.Field, key string) []nglog.Field {
+	fs := make([]nglog.Field, 0, len(fields))
+	for _, f := range fields {
+		if f.Key != key {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
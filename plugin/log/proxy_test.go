@@ -0,0 +1,149 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+// syncBuffer is written by the summary ticker and read by the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func newBufferLogger(t testing.TB, buf io.Writer) nglog.Logger {
+	zl, err := nglog.New(nglog.ZapLogger,
+		nglog.WithWriter(buf),
+		nglog.WithEncoder(nglog.JSONEncoder),
+		nglog.WithEncoderCfg(nglog.NewEncoderConfig()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zl
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return entry
+}
+
+func TestPLoggerSelect(t *testing.T) {
+	info, warn, tracing := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	pl := NewPLogger()
+	_ = pl.Register("info", newBufferLogger(t, info))
+	_ = pl.Register("warn", newBufferLogger(t, warn))
+	_ = pl.Register("tracing", newBufferLogger(t, tracing))
+
+	l := pl.WithField("a", 1).WithFields(map[string]interface{}{"a": 2, "b": "x"})
+	l.Infow("hello", "c", true)
+	entry := decodeLine(t, info)
+	if entry["a"] != float64(2) || entry["b"] != "x" || entry["c"] != true {
+		t.Fatalf("unexpected info entry %v", entry)
+	}
+
+	l.With(nglog.String("d", "y")).Warnf("warn %d", 1)
+	entry = decodeLine(t, warn)
+	if entry["msg"] != "warn 1" || entry["d"] != "y" || entry["a"] != float64(2) {
+		t.Fatalf("unexpected warn entry %v", entry)
+	}
+
+	pl.WithField("type", "tracing").Info("span")
+	entry = decodeLine(t, tracing)
+	if _, ok := entry["type"]; ok || entry["msg"] != "span" {
+		t.Fatalf("unexpected tracing entry %v", entry)
+	}
+}
+
+func TestPLoggerRateLimit(t *testing.T) {
+	interval := limitSummaryInterval
+	defer func() { limitSummaryInterval = interval }()
+	limitSummaryInterval = 50 * time.Millisecond
+
+	audit := new(syncBuffer)
+	pl := NewPLogger()
+	defer pl.Close()
+	_ = pl.Register("audit", newBufferLogger(t, audit))
+	if err := pl.SetRateLimits(map[string]RateLimit{"audit": {Rate: 20, Burst: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	l := pl.WithField("type", "audit")
+	for i := 0; i < 5; i++ {
+		l.Info("login")
+	}
+	if n := strings.Count(audit.String(), "\n"); n != 2 {
+		t.Fatalf("want 2 lines, got %d", n)
+	}
+
+	// written by the ticker without a later entry
+	audit.Reset()
+	time.Sleep(100 * time.Millisecond)
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "3 messages of audit dropped by rate limit") {
+		t.Fatalf("want a dropped summary, got %q", lines)
+	}
+	// at the level of the limited entries, accepted by their route
+	if !strings.Contains(lines[0], `"level":"info"`) {
+		t.Fatalf("want the summary at info, got %q", lines[0])
+	}
+}
+
+func TestPLoggerRateLimitFlush(t *testing.T) {
+	audit := new(bytes.Buffer)
+	pl := NewPLogger()
+	_ = pl.Register("audit", newBufferLogger(t, audit))
+	limits := map[string]RateLimit{"audit": {Rate: 0.001, Burst: 1}}
+	if err := pl.SetRateLimits(limits); err != nil {
+		t.Fatal(err)
+	}
+
+	l := pl.WithField("type", "audit")
+	l.Info("login")
+	l.Info("login")
+	audit.Reset()
+	// the replaced limiters are flushed
+	if err := pl.SetRateLimits(limits); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(audit.String(), "1 messages of audit dropped by rate limit") {
+		t.Fatalf("want a dropped summary, got %q", audit.String())
+	}
+
+	l.Info("login")
+	l.Warn("login")
+	audit.Reset()
+	_ = pl.Close()
+	if !strings.Contains(audit.String(), "1 messages of audit dropped by rate limit") || !strings.Contains(audit.String(), `"level":"warn"`) {
+		t.Fatalf("want a dropped summary on close, got %q", audit.String())
+	}
+}
@@ -0,0 +1,28 @@
+package log
+
+import (
+	"github.com/diycoder/elf/kit/log/writer/rotate/quota"
+)
+
+// newQuota limits the total size of the log dir and the archive dir, or keeps
+// minFree bytes of the file system, by removing the oldest rotated files of
+// all the loggers. The removals are reported through the error logger.
+func newQuota(maxBytes, minFree int64) (*quota.Quota, error) {
+	var dirs []string
+	if defaultArchiveDir != "" {
+		dirs = append(dirs, defaultArchiveDir)
+	}
+	return quota.New(defaultLogDir,
+		quota.WithMaxBytes(maxBytes),
+		quota.WithMinFree(minFree),
+		quota.WithDirs(dirs...),
+		// the crash reports are kept
+		quota.WithSkipDirs(crashSubDir),
+		quota.WithOnRemove(func(path string, size int64, reason string) {
+			Errorw("[log] quota removed rotated file", "file", path, "size", size, "reason", reason)
+		}),
+		quota.WithOnError(func(err error) {
+			Errorf("[log] quota: %v", err)
+		}),
+	)
+}
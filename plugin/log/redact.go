@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+// defaultRedact is used by the pipelines declaring no redact, set by the
+// log_redact_values flag.
+var defaultRedact *Redact
+
+// Redact masks sensitive data in the messages and fields of every logger of
+// a pipeline. Without it the keys of nglog.DefaultRedactKeys are masked.
+//
+//	redact:
+//	  keys: [password, token, "*_secret"]
+//	  values: [mobile, id_card, bank_card, email]
+//	  rules:
+//	    - name: order
+//	      pattern: 'order-(\d+)'
+//	      style: partial
+//	      tail: 4
+type Redact struct {
+	Disable bool `json:"disable"`
+	// Keys are case insensitive globs, empty masks nglog.DefaultRedactKeys
+	Keys []string `json:"keys"`
+	// KeyStyle is "all" (default), "partial" or "hash"
+	KeyStyle string `json:"key_style"`
+	// Values are the builtin value rules: mobile, id_card, bank_card, email
+	Values []string     `json:"values"`
+	Rules  []RedactRule `json:"rules"`
+}
+
+// RedactRule masks the parts of the values matching Pattern, or its first
+// group if it has one.
+type RedactRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Style   string `json:"style"`
+	Head    int    `json:"head"`
+	Tail    int    `json:"tail"`
+}
+
+func (r *Redact) redactor() (*nglog.Redactor, error) {
+	if r == nil {
+		return nglog.NewRedactor(nglog.DefaultKeyRules(), nil)
+	}
+	if r.Disable {
+		return nil, nil
+	}
+
+	style, err := nglog.ParseMaskStyle(r.KeyStyle)
+	if err != nil {
+		return nil, err
+	}
+	keys := r.Keys
+	if len(keys) == 0 {
+		keys = nglog.DefaultRedactKeys
+	}
+	keyRules := make([]nglog.KeyRule, len(keys))
+	for i, key := range keys {
+		keyRules[i] = nglog.KeyRule{Pattern: key, Mask: nglog.Mask{Style: style}}
+	}
+
+	var valueRules []nglog.ValueRule
+	for _, name := range r.Values {
+		rule, err := nglog.NewValueRule(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		valueRules = append(valueRules, rule)
+	}
+	for _, rr := range r.Rules {
+		re, err := regexp.Compile(rr.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact rule %s: %v", rr.Name, err)
+		}
+		style, err := nglog.ParseMaskStyle(rr.Style)
+		if err != nil {
+			return nil, fmt.Errorf("redact rule %s: %v", rr.Name, err)
+		}
+		valueRules = append(valueRules, nglog.ValueRule{
+			Name:   rr.Name,
+			Regexp: re,
+			Mask:   nglog.Mask{Style: style, Head: rr.Head, Tail: rr.Tail},
+		})
+	}
+	return nglog.NewRedactor(keyRules, valueRules)
+}
@@ -0,0 +1,206 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diycoder/elf/config"
+	"github.com/diycoder/elf/config/reader"
+	"github.com/diycoder/elf/config/source/file"
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+// Settings are the log settings which can be changed at runtime without
+// recreating the writers. Settings left empty are reset to their defaults.
+//
+//	level: info
+//	levels:
+//	  access: warn
+//	fields:
+//	  env: prod
+//	limits:
+//	  error: {rate: 100, burst: 200}
+type Settings struct {
+	// Level is the global level, empty keeps the level set by SetLogLevel
+	Level string `json:"level"`
+	// Levels are the levels of the types or route keys, e.g. "access"
+	Levels map[string]string `json:"levels"`
+	// Fields are added to every entry
+	Fields map[string]interface{} `json:"fields"`
+	// Limits are the rate limits of the types or route keys
+	Limits map[string]RateLimit `json:"limits"`
+}
+
+// ApplySettings applies s to the default logger.
+func ApplySettings(s *Settings) error {
+	pl, ok := defaultLog.(*pLogger)
+	if !ok {
+		return fmt.Errorf("logger %T does not support runtime settings", defaultLog)
+	}
+
+	lv := defaultLogLevel
+	if s.Level != "" {
+		var err error
+		if lv, err = nglog.ParseLevel(s.Level); err != nil {
+			return err
+		}
+	}
+	levels := make(map[string]nglog.Level, len(s.Levels))
+	for typ, level := range s.Levels {
+		l, err := nglog.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("type %s: %v", typ, err)
+		}
+		levels[typ] = l
+	}
+
+	limiters, err := newLimiters(s.Limits)
+	if err != nil {
+		return err
+	}
+	fields := make([]nglog.Field, 0, len(s.Fields))
+	for k, v := range s.Fields {
+		fields = append(fields, nglog.Any(k, v))
+	}
+
+	// applied at once, so a log call never sees half of the settings
+	old := pl.settings.Swap(&settings{level: lv, levels: levels, fields: fields, limiters: limiters})
+	pl.replaceLimiters(old.limiters, limiters)
+	return nil
+}
+
+// Watch applies the settings at path of c, e.g. a config loaded from a file,
+// etcd, apollo or nacos, and applies them again whenever they change until
+// the returned stop function is called.
+func Watch(c config.Config, path ...string) (func() error, error) {
+	if err := applyValue(c.Get(path...)); err != nil {
+		return nil, err
+	}
+
+	w, err := c.Watch(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped int32
+	go func() {
+		for {
+			v, err := w.Next()
+			if err != nil {
+				if atomic.LoadInt32(&stopped) == 0 {
+					Errorf("[log] watch settings: %v", err)
+				}
+				return
+			}
+			if err := applyValue(v); err != nil {
+				Errorf("[log] apply settings: %v", err)
+			}
+		}
+	}()
+
+	return func() error {
+		atomic.StoreInt32(&stopped, 1)
+		return w.Stop()
+	}, nil
+}
+
+// watchNamedInterval is the interval a config not registered yet is looked up
+// at, the config plugins are initialized after the log plugin.
+var watchNamedInterval = time.Second
+
+// WatchNamed watches the settings at path of the config registered with name,
+// e.g. "apollo" or "nacos". A config not registered yet is waited for in the
+// background until the returned stop function is called.
+func WatchNamed(name string, path ...string) (func() error, error) {
+	if c, ok := config.Named(name); ok {
+		return Watch(c, path...)
+	}
+
+	var (
+		mu   sync.Mutex
+		stop func() error
+		done = make(chan struct{})
+	)
+	go func() {
+		t := time.NewTicker(watchNamedInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-done:
+				return
+			}
+			c, ok := config.Named(name)
+			if !ok {
+				continue
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s, err := Watch(c, path...)
+			if err != nil {
+				Errorf("[log] watch %s settings: %v", name, err)
+				return
+			}
+			stop = s
+			return
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			close(done)
+			if stop != nil {
+				err = stop()
+			}
+		})
+		return err
+	}, nil
+}
+
+// WatchFile watches the settings in a json/yaml/toml file.
+func WatchFile(path string) (func() error, error) {
+	c, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Load(file.NewSource(file.WithPath(path))); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	stop, err := Watch(c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return func() error {
+		defer c.Close()
+		return stop()
+	}, nil
+}
+
+func applyValue(v reader.Value) error {
+	// a missing path, or a file read while being written, is not applied
+	if b := bytes.TrimSpace(v.Bytes()); len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+
+	s := new(Settings)
+	if err := v.Scan(s); err != nil {
+		return err
+	}
+	return ApplySettings(s)
+}
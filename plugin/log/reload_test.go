@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diycoder/elf/config"
+	"github.com/diycoder/elf/config/source/file"
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+func TestWatchFile(t *testing.T) {
+	info, access := new(bytes.Buffer), new(bytes.Buffer)
+	pl := NewPLogger()
+	_ = pl.Register("info", newBufferLogger(t, info))
+	_ = pl.Register("warn", newBufferLogger(t, info))
+	_ = pl.Register("access", newBufferLogger(t, access))
+
+	old := defaultLog
+	defer SetLogger(old)
+	SetLogger(pl)
+
+	conf := filepath.Join(t.TempDir(), "log.json")
+	if err := os.WriteFile(conf, []byte(`{"levels": {"access": "warn"}, "fields": {"env": "test"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := WatchFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// derived before the reload, still follows the settings
+	accessLog := pl.WithField("type", "access")
+	accessLog.Info("skipped")
+	if access.Len() != 0 {
+		t.Fatalf("access info should be dropped, got %q", access.String())
+	}
+	pl.WithField("env", "local").Info("hello")
+	if entry := decodeLine(t, info); entry["env"] != "local" {
+		t.Fatalf("logger field should override global field, got %v", entry)
+	}
+	pl.Info("hello")
+	if entry := decodeLine(t, info); entry["env"] != "test" {
+		t.Fatalf("global field missing, got %v", entry)
+	}
+
+	// the file is replaced until the watcher, started asynchronously,
+	// picks the change up
+	deadline := time.Now().Add(5 * time.Second)
+	for pl.settings.Load().level != nglog.WarnLevel && time.Now().Before(deadline) {
+		tmp := conf + ".tmp"
+		if err := os.WriteFile(tmp, []byte(`{"level": "warn", "levels": {"access": "debug"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, conf); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	info.Reset()
+
+	pl.Info("skipped")
+	accessLog.Info("logged")
+	if info.Len() != 0 || access.Len() == 0 {
+		t.Fatalf("settings not reloaded, info %q, access %q", info.String(), access.String())
+	}
+	if entry := decodeLine(t, access); entry["env"] != nil {
+		t.Fatalf("global field should be removed, got %v", entry)
+	}
+}
+
+func TestWatchNamed(t *testing.T) {
+	pl := NewPLogger()
+	old := defaultLog
+	defer SetLogger(old)
+	SetLogger(pl)
+
+	interval := watchNamedInterval
+	defer func() { watchNamedInterval = interval }()
+	watchNamedInterval = 10 * time.Millisecond
+
+	// registered after the log plugin is initialized, like apollo and nacos
+	stop, err := WatchNamed("log_test", "app", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	conf := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(conf, []byte(`{"app": {"log": {"level": "error"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Load(file.NewSource(file.WithPath(conf))); err != nil {
+		t.Fatal(err)
+	}
+	config.Register("log_test", c)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pl.settings.Load().level != nglog.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("settings not applied, level %v", pl.settings.Load().level)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelRoutes are the route keys selecting by level, the other keys select by
+// the "type" field. Panic and fatal are written at error level, so they share
+// the logger of error.
+var levelRoutes = map[string]zapcore.Level{
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"warn":  zapcore.WarnLevel,
+	"error": zapcore.ErrorLevel,
+	"panic": zapcore.ErrorLevel,
+	"fatal": zapcore.ErrorLevel,
+}
+
+const numLevels = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+
+// routeTable knows which levels and types are routed to a core.
+type routeTable struct {
+	types  map[string]bool
+	levels [numLevels]bool
+	cores  []*routeCore
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{types: make(map[string]bool)}
+}
+
+func (t *routeTable) levelRouted(l zapcore.Level) bool {
+	return l >= zapcore.DebugLevel && l <= zapcore.FatalLevel && t.levels[l-zapcore.DebugLevel]
+}
+
+// routeKey returns the route key the entries of a logger with typ at lv are
+// written with.
+func (t *routeTable) routeKey(typ string, lv string) string {
+	if typ != "" && t.types[typ] {
+		return typ
+	}
+	return lv
+}
+
+// add creates a core written by the levels and types routed to it.
+func (t *routeTable) add(core zapcore.Core, stack zapcore.Level) *routeCore {
+	rc := &routeCore{Core: core, table: t, stack: stack, types: make(map[string]bool)}
+	t.cores = append(t.cores, rc)
+	return rc
+}
+
+// addFallback creates a core writing the entries no route selects.
+func (t *routeTable) addFallback(core zapcore.Core) *routeCore {
+	rc := t.add(core, zapcore.ErrorLevel)
+	rc.fallback = true
+	return rc
+}
+
+func (t *routeTable) route(rc *routeCore, key string) {
+	if l, ok := levelRoutes[key]; ok {
+		rc.levels[l-zapcore.DebugLevel] = true
+		t.levels[l-zapcore.DebugLevel] = true
+		return
+	}
+	rc.types[key] = true
+	t.types[key] = true
+}
+
+// tee returns the core writing to every route core.
+func (t *routeTable) tee() zapcore.Core {
+	cores := make([]zapcore.Core, len(t.cores))
+	for i, rc := range t.cores {
+		cores[i] = rc
+	}
+	return zapcore.NewTee(cores...)
+}
+
+// stackEnabler records a stack trace for a level when a core accepting the
+// level wants one.
+func (t *routeTable) stackEnabler() zapcore.LevelEnabler {
+	var stack [numLevels]bool
+	for i := range stack {
+		l := zapcore.DebugLevel + zapcore.Level(i)
+		for _, rc := range t.cores {
+			accepts := rc.levels[i] || len(rc.types) > 0 || (rc.fallback && !t.levels[i])
+			if accepts && l >= rc.stack {
+				stack[i] = true
+			}
+		}
+	}
+	return zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		if l > zapcore.FatalLevel {
+			return true
+		}
+		return l >= zapcore.DebugLevel && stack[l-zapcore.DebugLevel]
+	})
+}
+
+// routeCore writes the entries routed to one logger of the pipeline. The
+// type of the logger is taken from the fields added by With, so selecting
+// a core costs no allocation per entry.
+type routeCore struct {
+	zapcore.Core
+	table    *routeTable
+	levels   [numLevels]bool
+	types    map[string]bool
+	stack    zapcore.Level
+	fallback bool
+	// typ is the value of the "type" field of the logger
+	typ string
+}
+
+func (c *routeCore) With(fields []zapcore.Field) zapcore.Core {
+	typ := c.typ
+	for _, f := range fields {
+		if f.Key == typeKey && f.Type == zapcore.StringType {
+			typ = f.String
+		}
+	}
+	// 如果是 tracing 就删除掉
+	if typ == "tracing" && c.types[typ] {
+		fields = withoutKey(fields, typeKey)
+	}
+
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	clone.typ = typ
+	return &clone
+}
+
+func (c *routeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.accepts(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c *routeCore) accepts(l zapcore.Level) bool {
+	if c.typ != "" && c.table.types[c.typ] {
+		return c.types[c.typ]
+	}
+	if c.fallback {
+		return !c.table.levelRouted(l)
+	}
+	return l >= zapcore.DebugLevel && l <= zapcore.FatalLevel && c.levels[l-zapcore.DebugLevel]
+}
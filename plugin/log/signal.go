@@ -0,0 +1,74 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+
+	rotatelogs "github.com/diycoder/elf/kit/log/writer/rotate/file-rotatelogs"
+)
+
+// Reopen closes the log files and opens them again by the same names, e.g.
+// in the postrotate script of logrotate after the files are moved away.
+func Reopen() error {
+	return eachFile(func(f *rotatelogs.RotateLogs) error {
+		return f.Reopen()
+	})
+}
+
+// Rotate forces the log files to rotate, they are written to "name.1",
+// "name.2" until the next rotation time.
+func Rotate() error {
+	return eachFile(func(f *rotatelogs.RotateLogs) error {
+		return f.Rotate()
+	})
+}
+
+func eachFile(fn func(*rotatelogs.RotateLogs) error) error {
+	closersMu.Lock()
+	files := activeFiles
+	closersMu.Unlock()
+
+	var errs []error
+	for _, f := range files {
+		if err := fn(f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleSignals reopens the log files on SIGHUP and rotates them on SIGUSR1
+// until the returned stop function is called.
+func handleSignals() (func() error, error) {
+	ch := make(chan os.Signal, 1)
+	if err := notifySignals(ch); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				var err error
+				if sig == reopenSignal {
+					err = Reopen()
+				} else {
+					err = Rotate()
+				}
+				if err != nil {
+					Errorf("[log] %v: %v", sig, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		signal.Stop(ch)
+		close(done)
+		return nil
+	}, nil
+}
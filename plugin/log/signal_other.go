@@ -0,0 +1,18 @@
+//go:build !unix
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+var reopenSignal os.Signal
+
+func notifySignals(ch chan<- os.Signal) error {
+	return errors.New("log signals are not supported on this platform")
+}
+
+func notifyDumpSignal(ch chan<- os.Signal) error {
+	return errors.New("log dump signal is not supported on this platform")
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var reopenSignal os.Signal = syscall.SIGHUP
+
+func notifySignals(ch chan<- os.Signal) error {
+	signal.Notify(ch, syscall.SIGHUP, syscall.SIGUSR1)
+	return nil
+}
+
+func notifyDumpSignal(ch chan<- os.Signal) error {
+	signal.Notify(ch, syscall.SIGQUIT)
+	return nil
+}
@@ -0,0 +1,116 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignals(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{Name: "app", Output: "file", SubDir: "app/", Filename: "app.log"}},
+		Routes:  map[string]string{"info": "app"},
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pl.files) != 1 {
+		t.Fatalf("%d files", len(pl.files))
+	}
+	closersMu.Lock()
+	files := activeFiles
+	activeFiles = pl.files
+	closersMu.Unlock()
+	defer func() {
+		closersMu.Lock()
+		activeFiles = files
+		closersMu.Unlock()
+	}()
+
+	stop, err := handleSignals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	pl.Info("before logrotate")
+	fn := pl.files[0].CurrentFileName()
+	if err := os.Rename(fn, fn+".old"); err != nil {
+		t.Fatal(err)
+	}
+
+	// SIGHUP reopens the file moved away
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		_, err := os.Stat(fn)
+		return err == nil
+	})
+	pl.Info("after logrotate")
+	if got := readLog(t, fn+".old"); !strings.Contains(got, "before logrotate") || strings.Contains(got, "after logrotate") {
+		t.Fatalf("moved file %q", got)
+	}
+	if got := readLog(t, fn); !strings.Contains(got, "after logrotate") {
+		t.Fatalf("reopened file %q", got)
+	}
+
+	// SIGUSR1 rotates to a generational file
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return pl.files[0].CurrentFileName() == fn+".1"
+	})
+	pl.Info("after rotate")
+	if got := readLog(t, filepath.Join(dir, "app", "app.log")); !strings.Contains(got, "after rotate") {
+		t.Fatalf("linked file %q", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("timed out")
+}
+
+func TestDumpSignal(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	stop, err := handleDumpSignal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// SIGQUIT writes a dump instead of exiting
+	if err := syscall.Kill(os.Getpid(), syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+	var dumps []string
+	waitFor(t, func() bool {
+		dumps, _ = filepath.Glob(filepath.Join(dir, crashSubDir, "*.goroutines"))
+		return len(dumps) == 1
+	})
+	waitFor(t, func() bool {
+		return strings.Contains(readLog(t, dumps[0]), "TestDumpSignal")
+	})
+}
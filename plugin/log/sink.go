@@ -0,0 +1,111 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diycoder/elf/kit/log/writer/httpbatch"
+	"github.com/diycoder/elf/kit/log/writer/socket"
+	"github.com/diycoder/elf/kit/log/writer/syslog"
+)
+
+// newSinkWriter creates the writer shipping the logs to rawURL:
+//
+//	syslog://host:514?facility=local0&severity=info&app=name (syslog+tcp, syslog+unix, syslog+unixgram:///dev/log)
+//	tcp://host:5170, udp://host:5170, unix:///path/to/socket (one line per entry)
+//	https://host/logs?batch_size=500&flush_interval=1s&retries=3&gzip=true
+func newSinkWriter(rawURL string) (io.WriteCloser, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("log sink url cannot be empty")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := u.Host
+	scheme := u.Scheme
+	network := scheme
+	if i := strings.Index(scheme, "+"); i >= 0 {
+		scheme, network = scheme[:i], scheme[i+1:]
+	} else if scheme == "syslog" {
+		network = "udp"
+	}
+	if strings.HasPrefix(network, "unix") {
+		addr = u.Path
+	}
+
+	q := u.Query()
+	switch scheme {
+	case "syslog":
+		opts := []syslog.Option{}
+		if v := q.Get("facility"); v != "" {
+			f, err := syslog.ParseFacility(v)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, syslog.WithFacility(f))
+		}
+		if v := q.Get("severity"); v != "" {
+			s, err := syslog.ParseSeverity(v)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, syslog.WithSeverity(s))
+		}
+		if v := q.Get("app"); v != "" {
+			opts = append(opts, syslog.WithAppName(v))
+		}
+		return syslog.NewWriter(network, addr, opts...)
+	case "tcp", "udp", "unix", "unixgram":
+		return socket.NewWriter(network, addr)
+	case "http", "https":
+		return newHTTPSinkWriter(u)
+	}
+	return nil, fmt.Errorf("invalid log sink %q", rawURL)
+}
+
+// newHTTPSinkWriter takes the batch settings off the query of u.
+func newHTTPSinkWriter(u *url.URL) (io.WriteCloser, error) {
+	q := u.Query()
+	opts := []httpbatch.Option{}
+	if v := q.Get("batch_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log sink batch_size %q", v)
+		}
+		opts = append(opts, httpbatch.WithBatchSize(n))
+	}
+	if v := q.Get("flush_interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, httpbatch.WithFlushInterval(d))
+	}
+	if v := q.Get("retries"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log sink retries %q", v)
+		}
+		opts = append(opts, httpbatch.WithRetry(n, httpbatch.DefaultBackoff))
+	}
+	if v := q.Get("gzip"); v != "" {
+		gz, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log sink gzip %q", v)
+		}
+		opts = append(opts, httpbatch.WithGzip(gz))
+	}
+	for _, key := range []string{"batch_size", "flush_interval", "retries", "gzip"} {
+		q.Del(key)
+	}
+
+	target := *u
+	target.RawQuery = q.Encode()
+	return httpbatch.NewWriter(target.String(), opts...)
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/diycoder/elf/kit/log/writer/async"
+)
+
+func TestPipelineSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{{Name: "app", Encoder: "json", Sink: "tcp://" + ln.Addr().String()}},
+		Routes:  map[string]string{"info": "app"},
+	}
+	pl, closers, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll(closers)
+	// a sink is written in the background by default
+	if len(closers) != 1 {
+		t.Fatalf("want an async writer, got %v", closers)
+	}
+	if _, ok := closers[0].(*async.Writer); !ok {
+		t.Fatalf("want an async writer, got %T", closers[0])
+	}
+
+	pl.Info("shipped")
+	if line := <-lines; !strings.Contains(line, `"msg":"shipped"`) {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- r.URL.RawQuery + " " + string(b)
+	}))
+	defer srv.Close()
+
+	w, err := newSinkWriter(srv.URL + "/logs?index=app&batch_size=1&gzip=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("line")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; got != "index=app line\n" {
+		t.Fatalf("unexpected request %q", got)
+	}
+
+	for _, sink := range []string{"", "ftp://host", "syslog://host:514?facility=mars", "https://host?retries=x"} {
+		if _, err := newSinkWriter(sink); err == nil {
+			t.Errorf("sink %q should be invalid", sink)
+		}
+	}
+}
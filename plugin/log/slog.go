@@ -0,0 +1,18 @@
+package log
+
+import (
+	"log/slog"
+
+	nglog "github.com/diycoder/elf/kit/log"
+)
+
+// replaceSlogLogger makes the default slog logger write through l, so the
+// libraries logging with slog share the log files.
+func replaceSlogLogger(l nglog.Logger) {
+	slog.SetDefault(slog.New(nglog.NewSlogHandler(l, nil)))
+}
+
+// SetSlogLogger makes the log plugin write to l instead of its loggers.
+func SetSlogLogger(l *slog.Logger) {
+	SetLogger(nglog.NewSlogLogger(l))
+}
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlogDefault(t *testing.T) {
+	dir := t.TempDir()
+	logDir := defaultLogDir
+	defer func() { defaultLogDir = logDir }()
+	defaultLogDir = dir + "/"
+
+	p := &Pipeline{
+		Loggers: []LoggerConfig{
+			{Name: "app", Output: "file", Filename: "app.log", Encoder: "json", NoDefaultFields: true},
+			{Name: "audit", Output: "file", Filename: "audit.log", Encoder: "json", NoDefaultFields: true},
+		},
+		Routes: map[string]string{"info": "app", "warn": "app", "audit": "audit"},
+	}
+	pl, _, err := p.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := slog.Default()
+	defer slog.SetDefault(old)
+	replaceSlogLogger(pl)
+
+	slog.Info("from slog", "k", 1)
+	slog.With("type", "audit").Warn("audited", slog.Group("user", "id", 7))
+
+	app := readLog(t, filepath.Join(dir, "app.log"))
+	for _, want := range []string{`"msg":"from slog"`, `"k":1`, "slog_test.go"} {
+		if !strings.Contains(app, want) {
+			t.Errorf("want %s in %s", want, app)
+		}
+	}
+	audit := readLog(t, filepath.Join(dir, "audit.log"))
+	if !strings.Contains(audit, `"msg":"audited"`) || !strings.Contains(audit, `"user.id":7`) {
+		t.Errorf("unexpected audit log %s", audit)
+	}
+}
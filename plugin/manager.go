@@ -9,6 +9,7 @@ type manager struct {
 	sync.Mutex
 	plugins    []Plugin
 	registered map[string]bool
+	states     map[string]State
 }
 
 // global plugin manager
@@ -17,6 +18,7 @@ var defaultManager = newManager()
 func newManager() *manager {
 	return &manager{
 		registered: make(map[string]bool),
+		states:     make(map[string]State),
 	}
 }
 
@@ -26,6 +28,19 @@ func (m *manager) Plugins() []Plugin {
 	return m.plugins
 }
 
+func (m *manager) active() []Plugin {
+	m.Lock()
+	defer m.Unlock()
+
+	plugins := make([]Plugin, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		if m.states[p.String()] != StateFailed {
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
 func (m *manager) Register(plugin Plugin) error {
 	m.Lock()
 	defer m.Unlock()
@@ -37,6 +52,7 @@ func (m *manager) Register(plugin Plugin) error {
 	}
 
 	m.registered[name] = true
+	m.states[name] = StateRegistered
 	m.plugins = append(m.plugins, plugin)
 	return nil
 }
@@ -47,3 +63,19 @@ func (m *manager) isRegistered(plugin Plugin) bool {
 
 	return m.registered[plugin.String()]
 }
+
+func (m *manager) setState(plugin Plugin, s State) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.registered[plugin.String()] {
+		m.states[plugin.String()] = s
+	}
+}
+
+func (m *manager) stateOf(plugin Plugin) State {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.states[plugin.String()]
+}
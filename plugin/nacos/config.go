@@ -1,12 +1,15 @@
 package nacos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/diycoder/elf/config"
 	"github.com/diycoder/elf/plugin"
+	nnet "github.com/diycoder/elf/utils/net"
 
 	"github.com/urfave/cli/v2"
 )
@@ -127,11 +130,41 @@ func (c *nacos) getConfigure(ctx *cli.Context) error {
 	return nil
 }
 
+// Check checks at least one of the nacos servers is reachable.
+func (c *nacos) Check(ctx context.Context) error {
+	if c.opts == nil {
+		return errors.New("nacos is not initialized")
+	}
+	var err error
+	for _, addr := range strings.Split(c.opts.Address, ",") {
+		if err = nnet.Reachable(ctx, addr); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Actions can be run through the admin plugin.
+func (c *nacos) Actions() map[string]plugin.Action {
+	return map[string]plugin.Action{
+		"sync": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if cfg == nil {
+				return nil, errors.New("nacos is not initialized")
+			}
+			return nil, Sync()
+		},
+	}
+}
+
 // Name of the plugin
 func (c *nacos) String() string {
 	return "nacos_config"
 }
 
+func init() {
+	plugin.RegisterFactory("nacos", NewPlugin)
+}
+
 func NewPlugin() plugin.Plugin {
 	c := &nacos{}
 	return c
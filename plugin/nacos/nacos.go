@@ -48,6 +48,7 @@ func load(opts *Options) error {
 	if err := cfg.Load(newSource(opts)); err != nil {
 		return err
 	}
+	config.Register("nacos", cfg)
 	return nil
 }
 
@@ -11,6 +11,7 @@ type Options struct {
 	Commands []*cli.Command
 	Handlers []Handler
 	Init     func(*cli.Context) error
+	Policy   Policy
 }
 
 type Option func(o *Options)
@@ -43,6 +44,13 @@ func WithName(n string) Option {
 	}
 }
 
+// WithPolicy sets what happens when the plugin fails to initialize
+func WithPolicy(p Policy) Option {
+	return func(o *Options) {
+		o.Policy = p
+	}
+}
+
 // WithInit sets the init function
 func WithInit(fn func(*cli.Context) error) Option {
 	return func(o *Options) {
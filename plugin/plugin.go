@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/urfave/cli/v2"
@@ -23,6 +24,20 @@ type Plugin interface {
 	String() string
 }
 
+// HealthChecker is an optional interface a plugin can implement to report
+// whether the resources it manages (connections, writers...) are healthy.
+// It is collected by the health plugin and served on the readiness probe.
+type HealthChecker interface {
+	// Check returns nil when healthy. The context carries the check timeout.
+	Check(ctx context.Context) error
+}
+
+// Closer is an optional interface a plugin can implement to release its
+// resources (servers, writers...) when the process exits, see elf.Close.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
 // Manager is the plugin manager which stores plugins and allows them to be retrieved.
 // This is used by all the components of micro.
 type Manager interface {
@@ -60,6 +75,10 @@ func (p *plugin) String() string {
 	return p.opts.Name
 }
 
+func (p *plugin) Policy() Policy {
+	return p.opts.Policy
+}
+
 func newPlugin(opts ...Option) Plugin {
 	options := Options{
 		Name: "default",
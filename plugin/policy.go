@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policy decides what happens when a plugin fails to initialize.
+type Policy int
+
+const (
+	// Required plugins abort the startup when they fail, this is the default.
+	Required Policy = iota
+	// Optional plugins are logged and skipped when they fail.
+	Optional
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Required:
+		return "required"
+	case Optional:
+		return "optional"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// PolicyOf returns the policy a plugin declares through a Policy() method,
+// plugins without one are Required.
+func PolicyOf(p Plugin) Policy {
+	if pp, ok := p.(interface{ Policy() Policy }); ok {
+		return pp.Policy()
+	}
+	return Required
+}
+
+const (
+	PhaseRegister = "register"
+	PhaseInit     = "init"
+)
+
+// InitError describes the failure of a plugin during startup.
+type InitError struct {
+	Plugin   string
+	Phase    string
+	Policy   Policy
+	Duration time.Duration
+	Err      error
+}
+
+func (e *InitError) Error() string {
+	return fmt.Sprintf("plugin %s %s failed after %v: %v", e.Plugin, e.Phase, e.Duration, e.Err)
+}
+
+// Unwrap returns the cause, so errors.Is and errors.As walk the chain.
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,54 @@
+package requestid
+
+import (
+	"net/http"
+
+	"github.com/diycoder/elf/plugin"
+	"github.com/urfave/cli/v2"
+)
+
+type requestID struct{}
+
+// Global Flags
+func (p *requestID) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "request_id_header",
+			Value:   DefaultHeader,
+			Usage:   "Set the header carrying the request id.",
+			EnvVars: []string{"REQUEST_ID_HEADER"},
+		},
+	}
+}
+
+// Sub-commands
+func (p *requestID) Commands() []*cli.Command {
+	return nil
+}
+
+// Handle is the middleware handler for HTTP requests, it puts the request
+// id in the context of the request.
+func (p *requestID) Handler() plugin.Handler {
+	return func(h http.Handler) http.Handler {
+		return Handler(h)
+	}
+}
+
+// Init called when command line args are parsed.
+func (p *requestID) Init(ctx *cli.Context) error {
+	SetHeader(ctx.String("request_id_header"))
+	return nil
+}
+
+// Name of the plugin
+func (p *requestID) String() string {
+	return "request_id"
+}
+
+func init() {
+	plugin.RegisterFactory("request_id", NewPlugin)
+}
+
+func NewPlugin() plugin.Plugin {
+	return &requestID{}
+}
@@ -0,0 +1,186 @@
+// Package requestid reads or generates the id of each request, stores it in
+// the context and sets it on the response, so every log line written with
+// log.WithContext carries it as request_id.
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/diycoder/elf/utils/shortid"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultHeader carries the request id on http requests and responses
+	DefaultHeader = "X-Request-Id"
+	// FieldName is the log field of the request id
+	FieldName = "request_id"
+	// maxLength bounds the ids accepted from the clients
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+var (
+	header  atomic.Value
+	counter uint64
+)
+
+func init() {
+	header.Store(DefaultHeader)
+	nglog.RegisterContextKey(FieldName, contextKey{})
+}
+
+// SetHeader sets the header carrying the request id, the grpc metadata key
+// is its lower case.
+func SetHeader(name string) {
+	if name != "" {
+		header.Store(name)
+	}
+}
+
+// Header returns the header carrying the request id.
+func Header() string {
+	return header.Load().(string)
+}
+
+// New generates a request id.
+func New() string {
+	id, err := shortid.Generate()
+	if err != nil {
+		// unique within the process
+		return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(atomic.AddUint64(&counter, 1), 36)
+	}
+	return id
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id of ctx, empty if none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid rejects the ids which are too long or carry control characters, so
+// the clients cannot forge log lines.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// orNew returns id when it is valid, a new id otherwise.
+func orNew(id string) string {
+	if valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Handler reads the request id from the request header or generates one,
+// then stores it in the context and on the request and response headers.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		name := Header()
+		id := orNew(r.Header.Get(name))
+		r.Header.Set(name, id)
+		rw.Header().Set(name, id)
+		next.ServeHTTP(rw, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Gin is the gin variant of Handler, the id is stored in the context of the
+// request and under FieldName in the gin context.
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := Header()
+		id := orNew(c.GetHeader(name))
+		c.Request.Header.Set(name, id)
+		c.Header(name, id)
+		c.Set(FieldName, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// fromIncoming reads the request id from the incoming metadata or generates
+// one, and sends it back in the header of the response.
+func fromIncoming(ctx context.Context) context.Context {
+	key := metadataKey()
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	id = orNew(id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(key, id))
+	return NewContext(ctx, id)
+}
+
+// UnaryServerInterceptor is the grpc unary variant of Handler.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(fromIncoming(ctx), req)
+	}
+}
+
+// StreamServerInterceptor is the grpc stream variant of Handler.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: fromIncoming(ss.Context())})
+	}
+}
+
+// UnaryClientInterceptor forwards the request id of the context to the
+// called service.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := FromContext(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, metadataKey(), id)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is the grpc stream variant of
+// UnaryClientInterceptor.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if id := FromContext(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, metadataKey(), id)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func metadataKey() string {
+	return strings.ToLower(Header())
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
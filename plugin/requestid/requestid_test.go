@@ -0,0 +1,119 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nglog "github.com/diycoder/elf/kit/log"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHandler(t *testing.T) {
+	var got string
+	h := Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+		if r.Header.Get(DefaultHeader) != got {
+			t.Errorf("request header %q, want %q", r.Header.Get(DefaultHeader), got)
+		}
+		if nglog.ContextFields(r.Context())[FieldName] != got {
+			t.Errorf("context fields %v", nglog.ContextFields(r.Context()))
+		}
+	}))
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"forged\nline", false},
+		{strings.Repeat("a", maxLength+1), false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set(DefaultHeader, tt.header)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r)
+
+		if got == "" || rw.Header().Get(DefaultHeader) != got {
+			t.Fatalf("%q: id %q, response header %q", tt.header, got, rw.Header().Get(DefaultHeader))
+		}
+		if tt.keep != (got == tt.header) {
+			t.Fatalf("%q: id %q", tt.header, got)
+		}
+	}
+}
+
+func TestGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(Gin())
+	e.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, FromContext(c.Request.Context())+","+c.GetString(FieldName))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(DefaultHeader, "gin-1")
+	rw := httptest.NewRecorder()
+	e.ServeHTTP(rw, r)
+	if rw.Body.String() != "gin-1,gin-1" || rw.Header().Get(DefaultHeader) != "gin-1" {
+		t.Fatalf("body %q, header %q", rw.Body.String(), rw.Header().Get(DefaultHeader))
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return FromContext(ctx), nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "grpc-1"))
+	got, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	if err != nil || got != "grpc-1" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	got, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	if err != nil || got == "" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var got []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		got = md.Get("x-request-id")
+		return nil
+	}
+	ctx := NewContext(context.Background(), "client-1")
+	if err := UnaryClientInterceptor()(ctx, "/svc/m", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "client-1" {
+		t.Fatalf("metadata %v", got)
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	var got []string
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		got = md.Get("x-request-id")
+		return nil, nil
+	}
+	ctx := NewContext(context.Background(), "client-1")
+	if _, err := StreamClientInterceptor()(ctx, &grpc.StreamDesc{}, nil, "/svc/m", streamer); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "client-1" {
+		t.Fatalf("metadata %v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package plugin
+
+import "fmt"
+
+// State is the lifecycle state of a registered plugin.
+type State int
+
+const (
+	StateRegistered State = iota
+	StateInitialized
+	StateFailed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateRegistered:
+		return "registered"
+	case StateInitialized:
+		return "initialized"
+	case StateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// SetState records the state of a global plugin.
+func SetState(plugin Plugin, s State) {
+	defaultManager.setState(plugin, s)
+}
+
+// StateOf returns the state of a global plugin.
+func StateOf(plugin Plugin) State {
+	return defaultManager.stateOf(plugin)
+}
+
+// Active returns the global plugins which did not fail to initialize, failed
+// Optional plugins are left out of handler chains, commands and Close.
+func Active() []Plugin {
+	return defaultManager.active()
+}
@@ -1,7 +1,9 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/diycoder/elf/plugin"
@@ -13,6 +15,7 @@ import (
 
 type store struct {
 	storeCfg string
+	md       *metadata
 }
 
 func (s *store) Flags() []cli.Flag {
@@ -56,24 +59,63 @@ func (s *store) loadStore(path string) error {
 		return err
 	}
 	for _, c := range md.Stores {
-		switch c.Type {
-		case "redis":
-			if err := redis.Load(c.Namespace, c.Key); err != nil {
-				return err
-			}
-		case "mysql":
-			if err := mysql.Load(c.Namespace, c.Key); err != nil {
-				return err
-			}
+		if err := load(c); err != nil {
+			return err
 		}
 	}
+	s.md = md
+	return nil
+}
+
+func load(c *storeConf) error {
+	switch c.Type {
+	case "redis":
+		return redis.Load(c.Namespace, c.Key)
+	case "mysql":
+		return mysql.Load(c.Namespace, c.Key)
+	}
 	return nil
 }
 
+// Actions can be run through the admin plugin.
+func (s *store) Actions() map[string]plugin.Action {
+	return map[string]plugin.Action{
+		"rebuild": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if s.md == nil {
+				return nil, errors.New("store is not initialized")
+			}
+			for _, c := range s.md.Stores {
+				if c.Type == args["type"] && c.Key == args["key"] {
+					return nil, load(c)
+				}
+			}
+			return nil, fmt.Errorf("store %s %s not found", args["type"], args["key"])
+		},
+	}
+}
+
+// Close closes the mysql and redis connection pools, including the pools
+// replaced by a rebuild.
+func (s *store) Close(ctx context.Context) error {
+	return errors.Join(mysql.Close(), redis.Close())
+}
+
+// Check pings the mysql and redis connection pools.
+func (s *store) Check(ctx context.Context) error {
+	if err := mysql.Ping(ctx); err != nil {
+		return err
+	}
+	return redis.Ping(ctx)
+}
+
 func (s *store) String() string {
 	return "store"
 }
 
+func init() {
+	plugin.RegisterFactory("store", NewPlugin)
+}
+
 func NewPlugin() plugin.Plugin {
 	c := &store{}
 	return c
@@ -1,7 +1,9 @@
 package mysql
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -17,9 +19,16 @@ import (
 
 var dbMap sync.Map
 
+// retired are the replaced pools, the callers may still hold them so they
+// stay open until Close
+var (
+	retiredMu sync.Mutex
+	retired   []*sqlx.DB
+)
+
 type dbconfig struct {
-	User            string `json:"user"`
-	Password        string `json:"password"`
+	User            string `json:"user,omitempty"`
+	Password        string `json:"password,omitempty"`
 	Protol          string `json:"protol"`
 	Host            string `json:"host"`
 	Port            string `json:"port"`
@@ -51,7 +60,11 @@ func Load(namespace, key string) error {
 		log.Errorf("mysql ping 失败 error: %v", err)
 		return err
 	}
-	dbMap.Store(key, db)
+	if old, ok := dbMap.Swap(key, db); ok {
+		retiredMu.Lock()
+		retired = append(retired, old.(*sqlx.DB))
+		retiredMu.Unlock()
+	}
 	log.Infof("rebuild %v mysql connection pool done", key)
 
 	return nil
@@ -78,7 +91,7 @@ func addDBTrace(c *dbconfig) (*sqlx.DB, error) {
 		otelsql.WithDBName(c.DBName),
 	)
 	if err != nil {
-		log.Errorf("mysql open:%s, err:%v", dsn, err)
+		log.Errorf("mysql open:%s(%s:%s)/%s, err:%v", c.Protol, c.Host, c.Port, c.DBName, err)
 		return nil, err
 	}
 	db.SetMaxOpenConns(c.MaxOpenConn)
@@ -89,23 +102,24 @@ func addDBTrace(c *dbconfig) (*sqlx.DB, error) {
 	return db, nil
 }
 
+// echoConfig logs the config without the user and the password.
 func echoConfig(c *dbconfig) {
-	bytes, err := json.Marshal(c)
-	if err != nil {
-		return
-	}
-	cfg := make(map[string]interface{})
-	err = json.Unmarshal(bytes, &cfg)
-	if err != nil {
-		return
-	}
-	delete(cfg, "user")
-	delete(cfg, "password")
-	byteStr, err := json.Marshal(&cfg)
-	if err != nil {
-		return
-	}
-	log.Infof("apollo mysql config %v", string(byteStr))
+	cfg := *c
+	cfg.User, cfg.Password = "", ""
+	log.Infow("apollo mysql config", "config", &cfg)
+}
+
+// Ping pings every mysql connection pool loaded.
+func Ping(ctx context.Context) error {
+	var err error
+	dbMap.Range(func(key, value interface{}) bool {
+		if e := value.(*sqlx.DB).PingContext(ctx); e != nil {
+			err = fmt.Errorf("mysql %v ping err:%v", key, e)
+			return false
+		}
+		return true
+	})
+	return err
 }
 
 // GetDB get mysql client by key
@@ -118,3 +132,25 @@ func GetDB(key string) (*sqlx.DB, error) {
 	log.Errorf("get mysql client:%v failed ", key)
 	return nil, fmt.Errorf("get mysql client:%v failed ", key)
 }
+
+// Close closes the mysql connection pools loaded, including the replaced ones.
+func Close() error {
+	var errs []error
+	dbMap.Range(func(key, value interface{}) bool {
+		if err := value.(*sqlx.DB).Close(); err != nil {
+			errs = append(errs, fmt.Errorf("mysql %v close err:%v", key, err))
+		}
+		dbMap.Delete(key)
+		return true
+	})
+
+	retiredMu.Lock()
+	defer retiredMu.Unlock()
+	for _, p := range retired {
+		if err := p.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	retired = nil
+	return errors.Join(errs...)
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,6 +16,13 @@ import (
 
 var redisMap sync.Map
 
+// retired are the replaced pools, the callers may still hold them so they
+// stay open until Close
+var (
+	retiredMu sync.Mutex
+	retired   []*rds.Client
+)
+
 func Load(namespace, key string) error {
 	if namespace == "" || key == "" {
 		return fmt.Errorf("invalid config")
@@ -31,7 +39,7 @@ func Load(namespace, key string) error {
 	}
 
 	if err := checkConfig(&conf); err != nil {
-		log.Errorf("invalid redis config: %v", val.String(""))
+		log.Errorf("invalid redis config %v: %v", key, err)
 		return err
 	}
 	client := newRedisPool(&conf)
@@ -40,34 +48,27 @@ func Load(namespace, key string) error {
 		log.Errorf("redis连接池连接失败 err: %v", ping.Err())
 		return ping.Err()
 	}
-	redisMap.Store(key, client)
+	if old, ok := redisMap.Swap(key, client); ok {
+		retiredMu.Lock()
+		retired = append(retired, old.(*rds.Client))
+		retiredMu.Unlock()
+	}
 	log.Infof("rebuild %v redis connection pool done", key)
 
 	return nil
 }
 
+// echoConfig logs the config without the password.
 func echoConfig(c *rdconfig) {
-	bytes, err := json.Marshal(c)
-	if err != nil {
-		return
-	}
-	cfg := make(map[string]interface{})
-	err = json.Unmarshal(bytes, &cfg)
-	if err != nil {
-		return
-	}
-	delete(cfg, "password")
-	byteStr, err := json.Marshal(&cfg)
-	if err != nil {
-		return
-	}
-	log.Infof("apollo redis config %v", string(byteStr))
+	cfg := *c
+	cfg.Password = ""
+	log.Infow("apollo redis config", "config", &cfg)
 }
 
 type rdconfig struct {
 	Addr         string `json:"addr"`
 	Db           int    `json:"db"`
-	Password     string `json:"password"`
+	Password     string `json:"password,omitempty"`
 	PoolSize     int    `json:"pool_size"`
 	MinIdleConns int    `json:"min_idle_conns"`
 	ReadTimeout  int    `json:"read_timeout"`
@@ -115,6 +116,19 @@ func newRedisPool(cfg *rdconfig) *rds.Client {
 	return redisCli
 }
 
+// Ping pings every redis connection pool loaded.
+func Ping(ctx context.Context) error {
+	var err error
+	redisMap.Range(func(key, value interface{}) bool {
+		if e := value.(*rds.Client).Ping(ctx).Err(); e != nil {
+			err = fmt.Errorf("redis %v ping err:%v", key, e)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
 // GetClient get redis client by key
 func GetClient(key string) (*rds.Client, error) {
 	value, ok := redisMap.Load(key)
@@ -125,3 +139,25 @@ func GetClient(key string) (*rds.Client, error) {
 	log.Errorf("get redis client:%v failed", key)
 	return nil, fmt.Errorf("get redis client:%v failed", key)
 }
+
+// Close closes the redis connection pools loaded, including the replaced ones.
+func Close() error {
+	var errs []error
+	redisMap.Range(func(key, value interface{}) bool {
+		if err := value.(*rds.Client).Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis %v close err:%v", key, err))
+		}
+		redisMap.Delete(key)
+		return true
+	})
+
+	retiredMu.Lock()
+	defer retiredMu.Unlock()
+	for _, p := range retired {
+		if err := p.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	retired = nil
+	return errors.Join(errs...)
+}
@@ -3,6 +3,7 @@ package version
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 
@@ -59,21 +60,30 @@ func (p *version) Handler() plugin.Handler {
 
 func (p *version) Init(ctx *cli.Context) error {
 	if ctx.Bool("version") {
-		fmt.Println("Version     : \t" + Version)
-		fmt.Println("Git   branch: \t" + GitBranch)
-		fmt.Println("Git revision: \t" + GitRevision)
-		fmt.Println("Go   version: \t" + GoVersion)
-		fmt.Println("Build   time: \t" + BuildTime)
-		fmt.Println("OS/Arch     : \t" + OSArch)
+		Print(os.Stdout)
 		os.Exit(0)
 	}
 	return nil
 }
 
+// Print writes the build information to w.
+func Print(w io.Writer) {
+	fmt.Fprintln(w, "Version     : \t"+Version)
+	fmt.Fprintln(w, "Git   branch: \t"+GitBranch)
+	fmt.Fprintln(w, "Git revision: \t"+GitRevision)
+	fmt.Fprintln(w, "Go   version: \t"+GoVersion)
+	fmt.Fprintln(w, "Build   time: \t"+BuildTime)
+	fmt.Fprintln(w, "OS/Arch     : \t"+OSArch)
+}
+
 func (p *version) String() string {
 	return "version"
 }
 
+func init() {
+	plugin.RegisterFactory("version", NewPlugin)
+}
+
 func NewPlugin() plugin.Plugin {
 	return &version{}
 }
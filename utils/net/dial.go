@@ -0,0 +1,16 @@
+package net
+
+import (
+	"context"
+	"net"
+)
+
+// Reachable checks whether a tcp connection can be established to addr.
+func Reachable(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}